@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"murim-helper/internal/delivery"
+	"murim-helper/internal/dto"
+	"murim-helper/internal/repository"
+	"murim-helper/internal/service"
+	"murim-helper/internal/service/cronjob"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X main.commit=$(git rev-parse --short HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them the VCS stamp added by go build is used when available.
+var commit, buildTime string
+
+// readinessChecks are the dependencies that must be healthy before this
+// instance gets traffic. Only Postgres is migrated; the other backends
+// create their schema when they open.
+func readinessChecks(repo repository.Repository, provider string, ai *service.GeneratorChain) []delivery.ReadinessCheck {
+	checks := []delivery.ReadinessCheck{{Name: "database", Check: repo.Ping}}
+	if pg, ok := repo.(*repository.PostgresRepo); ok {
+		checks = append(checks, delivery.ReadinessCheck{Name: "migrations", Check: pg.CheckMigrations})
+	}
+	checks = append(checks, delivery.ReadinessCheck{Name: "ai_provider", Check: func(context.Context) error {
+		// The chain skips providers that are not configured, so the selected
+		// one is missing from the front when its key is absent. The fallback
+		// still serves requests.
+		active := ai.Providers()
+		if len(active) == 0 {
+			return errors.New("no AI provider is configured")
+		}
+		if active[0] != provider {
+			return fmt.Errorf("%w: AI provider %s is not configured, falling back to %s", delivery.ErrDegraded, provider, active[0])
+		}
+		return nil
+	}})
+	return checks
+}
+
+// versionInfo describes the running build for /version
+func versionInfo(provider string, ai *service.GeneratorChain) dto.VersionResponse {
+	info := dto.VersionResponse{
+		Commit:      commit,
+		BuildTime:   buildTime,
+		GoVersion:   runtime.Version(),
+		AIProvider:  provider,
+		AIProviders: ai.Providers(),
+		DBDriver:    os.Getenv("DB_DRIVER"),
+	}
+	if info.DBDriver == "" {
+		info.DBDriver = "postgres"
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	for _, job := range cronjob.Jobs() {
+		info.Cron = append(info.Cron, dto.CronJobDTO{Name: job.Name, Schedule: job.Schedule})
+	}
+	return info
+}
+
+// selectedProvider reads AI_PROVIDER, defaulting to groq
+func selectedProvider() string {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("AI_PROVIDER"))) // groq, openai or ollama
+	if provider == "" {
+		provider = service.ProviderGroq
+	}
+	return provider
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"murim-helper/internal/repository"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+const migrateUsage = "usage: murim-helper migrate up | down [N] | version | force VERSION"
+
+// migrateLogger reports each applied migration through the standard logger
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	log.Printf("[Migrate] "+format, v...)
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
+
+// runMigrate implements the migrate subcommand against POSTGRES_CONN.
+// down rolls back one migration unless told how many; force marks VERSION
+// as applied and clean without running anything, to recover from a failed
+// migration or to adopt a database that was migrated by hand.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	var run func(m *migrate.Migrate) error
+	switch args[0] {
+	case "up":
+		run = (*migrate.Migrate).Up
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+			steps = n
+		}
+		run = func(m *migrate.Migrate) error { return m.Steps(-steps) }
+	case "version":
+		run = func(*migrate.Migrate) error { return nil }
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		run = func(m *migrate.Migrate) error { return m.Force(version) }
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if driver := os.Getenv("DB_DRIVER"); driver != "" && driver != "postgres" {
+		return fmt.Errorf("migrations only apply to postgres, DB_DRIVER is %q", driver)
+	}
+	repo, err := repository.NewPostgresRepo(os.Getenv("POSTGRES_CONN"), poolConfig())
+	if err != nil {
+		return err
+	}
+	if err := waitForDB(repo); err != nil {
+		return err
+	}
+	m, err := repo.Migrator(context.Background())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	m.Log = migrateLogger{}
+
+	if err := run(m); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return logVersion(m)
+}
+
+// migrateUp applies pending migrations at startup
+func migrateUp(repo *repository.PostgresRepo) error {
+	m, err := repo.Migrator(context.Background())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	m.Log = migrateLogger{}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return logVersion(m)
+}
+
+func logVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		log.Println("Database has no migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		log.Printf("Database schema is at version %d but dirty; repair it, then run \"migrate force VERSION\"", version)
+		return nil
+	}
+	log.Printf("Database schema is at version %d", version)
+	return nil
+}
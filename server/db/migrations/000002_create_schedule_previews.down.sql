@@ -0,0 +1 @@
+DROP TABLE IF EXISTS schedule_previews;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE schedule_previews (
+    token TEXT PRIMARY KEY,
+    provider TEXT NOT NULL DEFAULT '',
+    items JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_schedule_previews_expires_at ON schedule_previews (expires_at);
@@ -0,0 +1,12 @@
+CREATE TABLE user_profiles (
+    id TEXT PRIMARY KEY,
+    occupation TEXT NOT NULL DEFAULT '',
+    wake_time TEXT NOT NULL,
+    sleep_time TEXT NOT NULL,
+    timezone TEXT NOT NULL,
+    work_start TEXT NOT NULL DEFAULT '',
+    work_end TEXT NOT NULL DEFAULT '',
+    habits JSONB NOT NULL DEFAULT '[]',
+    blocked_times JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
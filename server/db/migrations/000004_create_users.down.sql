@@ -0,0 +1,10 @@
+ALTER TABLE user_profiles DROP CONSTRAINT IF EXISTS user_profiles_user_id_fkey;
+UPDATE user_profiles SET user_id = 'default' WHERE user_id = '00000000-0000-0000-0000-000000000000';
+ALTER TABLE user_profiles RENAME COLUMN user_id TO id;
+
+ALTER TABLE schedule_previews DROP COLUMN IF EXISTS user_id;
+
+DROP INDEX IF EXISTS idx_schedules_user_start_time;
+ALTER TABLE schedules DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS users;
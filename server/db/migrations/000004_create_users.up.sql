@@ -0,0 +1,24 @@
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Data created before accounts existed belongs to the default user
+INSERT INTO users (id, name, email)
+VALUES ('00000000-0000-0000-0000-000000000000', 'Default user', 'default@murimhelper.local');
+
+ALTER TABLE schedules ADD COLUMN user_id TEXT REFERENCES users(id) ON DELETE CASCADE;
+UPDATE schedules SET user_id = '00000000-0000-0000-0000-000000000000';
+ALTER TABLE schedules ALTER COLUMN user_id SET NOT NULL;
+CREATE INDEX idx_schedules_user_start_time ON schedules (user_id, start_time);
+
+ALTER TABLE schedule_previews ADD COLUMN user_id TEXT REFERENCES users(id) ON DELETE CASCADE;
+UPDATE schedule_previews SET user_id = '00000000-0000-0000-0000-000000000000';
+ALTER TABLE schedule_previews ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE user_profiles RENAME COLUMN id TO user_id;
+UPDATE user_profiles SET user_id = '00000000-0000-0000-0000-000000000000' WHERE user_id = 'default';
+ALTER TABLE user_profiles
+    ADD CONSTRAINT user_profiles_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,8 @@
+-- Rules without a daily/weekly equivalent become one-off schedules
+UPDATE schedules SET repeat_type = 'none', repeat_until = NULL
+WHERE repeat_type NOT IN ('none', 'daily', 'weekly')
+   OR rrule LIKE '%INTERVAL=%'
+   OR rrule LIKE '%BY%'
+   OR rrule LIKE '%COUNT=%';
+
+ALTER TABLE schedules DROP COLUMN rrule;
@@ -0,0 +1,10 @@
+ALTER TABLE schedules ADD COLUMN rrule TEXT NOT NULL DEFAULT '';
+
+-- Convert the old repeat types; repeat_until has no zone, so UNTIL is floating
+UPDATE schedules
+SET rrule = 'FREQ=' || UPPER(repeat_type)
+    || COALESCE(';UNTIL=' || TO_CHAR(repeat_until, 'YYYYMMDD"T"HH24MISS'), '')
+WHERE repeat_type IN ('daily', 'weekly');
+
+UPDATE schedules SET repeat_type = 'none', repeat_until = NULL
+WHERE repeat_type NOT IN ('none', 'daily', 'weekly');
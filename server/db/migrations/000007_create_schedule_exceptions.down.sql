@@ -0,0 +1,2 @@
+-- Copies collapsed by the up migration are not restored
+DROP TABLE IF EXISTS schedule_exceptions;
@@ -0,0 +1,37 @@
+CREATE TABLE schedule_exceptions (
+    schedule_id TEXT NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    occurrence_start TIMESTAMP NOT NULL,
+    title TEXT,
+    description TEXT,
+    start_time TIMESTAMP,
+    end_time TIMESTAMP,
+    is_done BOOLEAN NOT NULL DEFAULT FALSE,
+    cancelled BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (schedule_id, occurrence_start)
+);
+
+CREATE INDEX idx_schedule_exceptions_start_time ON schedule_exceptions (start_time);
+
+-- The old midnight job copied recurring schedules forward as new recurring
+-- rows. Keep the earliest row of each chain as the series, carry the done
+-- state of the copies over as exceptions and drop the copies.
+CREATE TEMPORARY TABLE recurring_chains AS
+SELECT id, start_time, is_done,
+       FIRST_VALUE(id) OVER (
+           PARTITION BY user_id, title, repeat_type, start_time::time
+           ORDER BY start_time, created_at
+       ) AS series_id
+FROM schedules
+WHERE rrule <> '';
+
+INSERT INTO schedule_exceptions (schedule_id, occurrence_start, is_done)
+SELECT series_id, start_time, TRUE
+FROM recurring_chains
+WHERE id <> series_id AND is_done
+ON CONFLICT DO NOTHING;
+
+DELETE FROM schedules
+WHERE id IN (SELECT id FROM recurring_chains WHERE id <> series_id);
+
+DROP TABLE recurring_chains;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_schedules_user_series;
+ALTER TABLE schedules DROP COLUMN IF EXISTS series_id;
@@ -0,0 +1,4 @@
+ALTER TABLE schedules ADD COLUMN series_id TEXT;
+UPDATE schedules SET series_id = id;
+ALTER TABLE schedules ALTER COLUMN series_id SET NOT NULL;
+CREATE INDEX idx_schedules_user_series ON schedules (user_id, series_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS schedule_occurrences;
+DROP TABLE IF EXISTS cron_runs;
@@ -0,0 +1,16 @@
+CREATE TABLE cron_runs (
+    job_name TEXT PRIMARY KEY,
+    last_success_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE schedule_occurrences (
+    schedule_id TEXT NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    occurrence_start TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    PRIMARY KEY (schedule_id, occurrence_start)
+);
+
+CREATE INDEX idx_schedule_occurrences_start ON schedule_occurrences (occurrence_start);
+CREATE INDEX idx_schedule_occurrences_user_start_time ON schedule_occurrences (user_id, start_time);
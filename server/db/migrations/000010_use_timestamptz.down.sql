@@ -0,0 +1,67 @@
+ALTER TABLE schedules DROP COLUMN IF EXISTS timezone;
+
+CREATE TEMPORARY TABLE user_zones AS
+SELECT u.id AS user_id, COALESCE(NULLIF(p.timezone, ''), 'Asia/Jakarta') AS tz
+FROM users u
+LEFT JOIN user_profiles p ON p.user_id = u.id;
+
+-- Write schedule times back as wall-clock time in their owner's timezone,
+-- still as TIMESTAMPTZ labelled UTC so the type change below keeps them
+UPDATE schedules s
+SET start_time = (s.start_time AT TIME ZONE z.tz) AT TIME ZONE 'UTC',
+    end_time = (s.end_time AT TIME ZONE z.tz) AT TIME ZONE 'UTC',
+    repeat_until = (s.repeat_until AT TIME ZONE z.tz) AT TIME ZONE 'UTC'
+FROM user_zones z
+WHERE z.user_id = s.user_id;
+
+UPDATE schedule_exceptions e
+SET occurrence_start = (e.occurrence_start AT TIME ZONE z.tz) AT TIME ZONE 'UTC',
+    start_time = (e.start_time AT TIME ZONE z.tz) AT TIME ZONE 'UTC',
+    end_time = (e.end_time AT TIME ZONE z.tz) AT TIME ZONE 'UTC'
+FROM schedules s
+JOIN user_zones z ON z.user_id = s.user_id
+WHERE s.id = e.schedule_id;
+
+UPDATE schedule_occurrences o
+SET occurrence_start = (o.occurrence_start AT TIME ZONE z.tz) AT TIME ZONE 'UTC',
+    start_time = (o.start_time AT TIME ZONE z.tz) AT TIME ZONE 'UTC',
+    end_time = (o.end_time AT TIME ZONE z.tz) AT TIME ZONE 'UTC'
+FROM user_zones z
+WHERE z.user_id = o.user_id;
+
+DROP TABLE user_zones;
+
+ALTER TABLE schedules
+    ALTER COLUMN start_time TYPE TIMESTAMP USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMP USING end_time AT TIME ZONE 'UTC',
+    ALTER COLUMN repeat_until TYPE TIMESTAMP USING repeat_until AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE schedule_exceptions
+    ALTER COLUMN occurrence_start TYPE TIMESTAMP USING occurrence_start AT TIME ZONE 'UTC',
+    ALTER COLUMN start_time TYPE TIMESTAMP USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMP USING end_time AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE schedule_occurrences
+    ALTER COLUMN occurrence_start TYPE TIMESTAMP USING occurrence_start AT TIME ZONE 'UTC',
+    ALTER COLUMN start_time TYPE TIMESTAMP USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMP USING end_time AT TIME ZONE 'UTC';
+
+ALTER TABLE schedule_previews
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE user_profiles
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE api_keys
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE TIMESTAMP USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE TIMESTAMP USING revoked_at AT TIME ZONE 'UTC';
+
+ALTER TABLE cron_runs
+    ALTER COLUMN last_success_at TYPE TIMESTAMP USING last_success_at AT TIME ZONE 'UTC';
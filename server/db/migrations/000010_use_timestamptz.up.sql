@@ -0,0 +1,71 @@
+-- Timestamps used to be stored without a zone. Schedule times were written
+-- as wall-clock time in the owner's timezone (Asia/Jakarta unless their
+-- profile says otherwise); bookkeeping columns were written in UTC.
+CREATE TEMPORARY TABLE user_zones AS
+SELECT u.id AS user_id, COALESCE(NULLIF(p.timezone, ''), 'Asia/Jakarta') AS tz
+FROM users u
+LEFT JOIN user_profiles p ON p.user_id = u.id;
+
+ALTER TABLE schedules
+    ALTER COLUMN start_time TYPE TIMESTAMPTZ USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMPTZ USING end_time AT TIME ZONE 'UTC',
+    ALTER COLUMN repeat_until TYPE TIMESTAMPTZ USING repeat_until AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE schedule_exceptions
+    ALTER COLUMN occurrence_start TYPE TIMESTAMPTZ USING occurrence_start AT TIME ZONE 'UTC',
+    ALTER COLUMN start_time TYPE TIMESTAMPTZ USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMPTZ USING end_time AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE schedule_occurrences
+    ALTER COLUMN occurrence_start TYPE TIMESTAMPTZ USING occurrence_start AT TIME ZONE 'UTC',
+    ALTER COLUMN start_time TYPE TIMESTAMPTZ USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMPTZ USING end_time AT TIME ZONE 'UTC';
+
+ALTER TABLE schedule_previews
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE user_profiles
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE api_keys
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE TIMESTAMPTZ USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ USING revoked_at AT TIME ZONE 'UTC';
+
+ALTER TABLE cron_runs
+    ALTER COLUMN last_success_at TYPE TIMESTAMPTZ USING last_success_at AT TIME ZONE 'UTC';
+
+-- Reinterpret schedule wall-clock times in their owner's timezone
+UPDATE schedules s
+SET start_time = (s.start_time AT TIME ZONE 'UTC') AT TIME ZONE z.tz,
+    end_time = (s.end_time AT TIME ZONE 'UTC') AT TIME ZONE z.tz,
+    repeat_until = (s.repeat_until AT TIME ZONE 'UTC') AT TIME ZONE z.tz
+FROM user_zones z
+WHERE z.user_id = s.user_id;
+
+UPDATE schedule_exceptions e
+SET occurrence_start = (e.occurrence_start AT TIME ZONE 'UTC') AT TIME ZONE z.tz,
+    start_time = (e.start_time AT TIME ZONE 'UTC') AT TIME ZONE z.tz,
+    end_time = (e.end_time AT TIME ZONE 'UTC') AT TIME ZONE z.tz
+FROM schedules s
+JOIN user_zones z ON z.user_id = s.user_id
+WHERE s.id = e.schedule_id;
+
+UPDATE schedule_occurrences o
+SET occurrence_start = (o.occurrence_start AT TIME ZONE 'UTC') AT TIME ZONE z.tz,
+    start_time = (o.start_time AT TIME ZONE 'UTC') AT TIME ZONE z.tz,
+    end_time = (o.end_time AT TIME ZONE 'UTC') AT TIME ZONE z.tz
+FROM user_zones z
+WHERE z.user_id = o.user_id;
+
+-- Recurrences are expanded in the zone the schedule was planned in
+ALTER TABLE schedules ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+UPDATE schedules s SET timezone = z.tz FROM user_zones z WHERE z.user_id = s.user_id;
+
+DROP TABLE user_zones;
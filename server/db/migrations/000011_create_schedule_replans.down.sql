@@ -0,0 +1 @@
+DROP TABLE IF EXISTS schedule_replans;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE schedule_replans (
+    token TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    instruction TEXT NOT NULL,
+    provider TEXT NOT NULL DEFAULT '',
+    diff JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_schedule_replans_expires_at ON schedule_replans (expires_at);
@@ -0,0 +1,4 @@
+-- Recurring schedules are expanded from their rule when read, so nothing
+-- needs to be materialized ahead of time
+DROP TABLE IF EXISTS schedule_occurrences;
+DROP TABLE IF EXISTS cron_runs;
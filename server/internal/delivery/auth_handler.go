@@ -0,0 +1,108 @@
+package delivery
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"murim-helper/internal/usecase"
+	"murim-helper/pkg/httphelper"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type AuthHandler struct {
+	Usecase usecase.AuthUsecase
+}
+
+func NewAuthHandler(r *mux.Router, uc usecase.AuthUsecase) {
+	handler := &AuthHandler{Usecase: uc}
+
+	s := r.PathPrefix("/auth").Subrouter()
+	s.HandleFunc("/keys", handler.ListKeys).Methods("GET")
+	s.HandleFunc("/keys", handler.CreateKey).Methods("POST")
+	s.HandleFunc("/keys/{id}", handler.RevokeKey).Methods("DELETE")
+	s.HandleFunc("/token", handler.IssueToken).Methods("POST")
+}
+
+func (h *AuthHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	keys, err := h.Usecase.ListAPIKeys(ctx, userIDFromRequest(r))
+	if err != nil {
+		log.Printf("[ListKeys] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch API keys", 50018)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched API keys", dto.ToAPIKeyResponses(keys))
+}
+
+func (h *AuthHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40015)
+		return
+	}
+
+	key, plaintext, err := h.Usecase.IssueAPIKey(ctx, userIDFromRequest(r), req.Name)
+	if err != nil {
+		log.Printf("[CreateKey] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to issue API key", 50019)
+		return
+	}
+	httphelper.Success(w, r, http.StatusCreated, "Successfully issued API key", dto.ToIssuedAPIKeyResponse(*key, plaintext))
+}
+
+func (h *AuthHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	err := h.Usecase.RevokeAPIKey(ctx, userIDFromRequest(r), getIDParam(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			httphelper.Error(w, r, http.StatusNotFound, "API key not found", 40407)
+		case errors.Is(err, domain.ErrForbidden):
+			httphelper.Error(w, r, http.StatusForbidden, "API key belongs to another user", 40302)
+		default:
+			log.Printf("[RevokeKey] error: %v", err)
+			httphelper.Error(w, r, http.StatusInternalServerError, "Failed to revoke API key", 50020)
+		}
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully revoked API key", nil)
+}
+
+func (h *AuthHandler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	token, expiresAt, err := h.Usecase.IssueToken(ctx, principalFromRequest(r))
+	if err != nil {
+		if errors.Is(err, domain.ErrTokensDisabled) {
+			httphelper.Error(w, r, http.StatusNotImplemented, "Bearer tokens are not configured", 50101)
+			return
+		}
+		if errors.Is(err, domain.ErrAPIKeyRequired) {
+			httphelper.Error(w, r, http.StatusForbidden, "Tokens can only be issued with an API key", 40303)
+			return
+		}
+		log.Printf("[IssueToken] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to issue token", 50021)
+		return
+	}
+	httphelper.Success(w, r, http.StatusCreated, "Successfully issued token", dto.TokenResponse{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,103 @@
+package delivery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"murim-helper/internal/dto"
+	"murim-helper/pkg/httphelper"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ErrDegraded is wrapped by a check whose dependency still works in a
+// reduced way. It is reported as "degraded" without failing readiness.
+var ErrDegraded = errors.New("degraded")
+
+// ReadinessCheck is one dependency /readyz verifies before traffic is
+// routed to this instance
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	Checks  []ReadinessCheck
+	DBStats func() sql.DBStats
+	Version dto.VersionResponse
+}
+
+// NewHealthHandler registers the probes used by the orchestrator. They must
+// be mounted outside authentication.
+func NewHealthHandler(r *mux.Router, checks []ReadinessCheck, dbStats func() sql.DBStats, version dto.VersionResponse) {
+	handler := &HealthHandler{Checks: checks, DBStats: dbStats, Version: version}
+
+	r.HandleFunc("/healthz", handler.Live).Methods("GET")
+	r.HandleFunc("/readyz", handler.Ready).Methods("GET")
+	r.HandleFunc("/version", handler.GetVersion).Methods("GET")
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Answers as long as the server is serving requests. It does not check any dependency.
+// @Tags health
+// @Produce json
+// @Success 200 {object} domain.ApiResponse
+// @Router /healthz [get]
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	httphelper.Success(w, r, http.StatusOK, "OK", nil)
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Checks that the database is reachable, its migrations are current and an AI provider is configured. A missing primary AI provider is reported as degraded without failing. Only the status of each check is returned; the reason a check failed is logged.
+// @Tags health
+// @Produce json
+// @Success 200 {object} dto.ReadinessResponse
+// @Failure 503 {object} httphelper.ErrorResponse
+// @Router /readyz [get]
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 3*time.Second)
+	defer cancel()
+
+	resp := dto.ReadinessResponse{
+		Checks: make(map[string]dto.CheckResult, len(h.Checks)),
+		DBPool: dto.ToDBPoolStats(h.DBStats()),
+	}
+	ready := true
+	for _, c := range h.Checks {
+		err := c.Check(ctx)
+		if errors.Is(err, ErrDegraded) {
+			log.Printf("[Readyz] %s: %v", c.Name, err)
+			resp.Checks[c.Name] = dto.CheckResult{Status: "degraded"}
+			continue
+		}
+		if err != nil {
+			log.Printf("[Readyz] %s check failed: %v", c.Name, err)
+			resp.Checks[c.Name] = dto.CheckResult{Status: "fail"}
+			ready = false
+			continue
+		}
+		resp.Checks[c.Name] = dto.CheckResult{Status: "ok"}
+	}
+
+	if !ready {
+		httphelper.ErrorWithMeta(w, r, http.StatusServiceUnavailable, "Service is not ready", 50301, resp)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Service is ready", resp)
+}
+
+// GetVersion godoc
+// @Summary Build information
+// @Description Returns the git commit and build time of the running binary, the AI providers in use and the cron schedule
+// @Tags health
+// @Produce json
+// @Success 200 {object} dto.VersionResponse
+// @Router /version [get]
+func (h *HealthHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched version", h.Version)
+}
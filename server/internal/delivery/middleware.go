@@ -0,0 +1,128 @@
+package delivery
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/usecase"
+	"murim-helper/pkg/httphelper"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type ctxKey string
+
+const (
+	userIDKey    ctxKey = "user_id"
+	principalKey ctxKey = "principal"
+)
+
+// AuthMiddleware authenticates requests with an API key or a signed bearer
+// token, sent as "Authorization: Bearer <credential>" or "X-API-Key".
+func AuthMiddleware(auth usecase.AuthUsecase) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			credential := credentialFromRequest(r)
+			if credential == "" {
+				httphelper.Error(w, r, http.StatusUnauthorized, "Missing credentials", 40102)
+				return
+			}
+
+			principal, err := auth.Authenticate(r.Context(), credential)
+			if err != nil {
+				switch {
+				case errors.Is(err, domain.ErrTokenExpired):
+					httphelper.Error(w, r, http.StatusUnauthorized, "Token has expired", 40104)
+				case errors.Is(err, domain.ErrAPIKeyRevoked):
+					httphelper.Error(w, r, http.StatusUnauthorized, "API key has been revoked", 40105)
+				case errors.Is(err, domain.ErrInvalidCredentials):
+					httphelper.Error(w, r, http.StatusUnauthorized, "Invalid credentials", 40103)
+				default:
+					log.Printf("[AuthMiddleware] error: %v", err)
+					httphelper.Error(w, r, http.StatusInternalServerError, "Failed to authenticate request", 50017)
+				}
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDKey, principal.UserID)
+			ctx = context.WithValue(ctx, principalKey, *principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AdminMiddleware guards operator-only endpoints such as user registration
+// with the static admin key. An empty adminKey closes them entirely.
+func AdminMiddleware(adminKey string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			credential := r.Header.Get("X-Admin-Key")
+			if credential == "" {
+				credential = credentialFromRequest(r)
+			}
+			if credential == "" {
+				httphelper.Error(w, r, http.StatusUnauthorized, "Missing credentials", 40102)
+				return
+			}
+			if adminKey == "" || subtle.ConstantTimeCompare([]byte(credential), []byte(adminKey)) != 1 {
+				httphelper.Error(w, r, http.StatusForbidden, "Admin privileges required", 40301)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserMiddleware trusts the X-User-ID header instead of authenticating. It
+// is only used when authentication is disabled for local development;
+// requests without the header act as the default user.
+func UserMiddleware(users usecase.UserUsecase) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := strings.TrimSpace(r.Header.Get("X-User-ID"))
+			if userID == "" {
+				userID = domain.DefaultUserID
+			}
+
+			if _, err := users.GetUser(r.Context(), userID); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					httphelper.Error(w, r, http.StatusUnauthorized, "Unknown user", 40101)
+					return
+				}
+				log.Printf("[UserMiddleware] error: %v", err)
+				httphelper.Error(w, r, http.StatusInternalServerError, "Failed to resolve user", 50016)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func credentialFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, credential, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(credential)
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+func userIDFromRequest(r *http.Request) string {
+	userID, _ := r.Context().Value(userIDKey).(string)
+	return userID
+}
+
+// principalFromRequest is empty when authentication is disabled
+func principalFromRequest(r *http.Request) domain.Principal {
+	p, _ := r.Context().Value(principalKey).(domain.Principal)
+	return p
+}
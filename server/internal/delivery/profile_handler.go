@@ -0,0 +1,62 @@
+package delivery
+
+import (
+	"encoding/json"
+	"log"
+	"murim-helper/internal/dto"
+	"murim-helper/internal/usecase"
+	"murim-helper/pkg/httphelper"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type ProfileHandler struct {
+	Usecase usecase.ProfileUsecase
+}
+
+func NewProfileHandler(r *mux.Router, uc usecase.ProfileUsecase) {
+	handler := &ProfileHandler{Usecase: uc}
+
+	r.HandleFunc("/profile", handler.Get).Methods("GET")
+	r.HandleFunc("/profile", handler.Update).Methods("PUT")
+}
+
+func (h *ProfileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	profile, err := h.Usecase.GetProfile(ctx, userIDFromRequest(r))
+	if err != nil {
+		log.Printf("[GetProfile] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch profile", 50013)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched profile", dto.ToUserProfileResponse(*profile))
+}
+
+func (h *ProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	var req dto.UserProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40011)
+		return
+	}
+
+	profile := req.ToDomain()
+	if err := profile.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40012)
+		return
+	}
+
+	updated, err := h.Usecase.UpdateProfile(ctx, userIDFromRequest(r), profile)
+	if err != nil {
+		log.Printf("[UpdateProfile] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to update profile", 50014)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully updated profile", dto.ToUserProfileResponse(*updated))
+}
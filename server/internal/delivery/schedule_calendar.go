@@ -0,0 +1,298 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"murim-helper/pkg/httphelper"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultCalendarLimit = 200
+	maxCalendarLimit     = 1000
+)
+
+// rangeParser turns the request's path or query into the bounds of a
+// calendar view in loc
+type rangeParser func(r *http.Request, loc *time.Location) (time.Time, time.Time, error)
+
+// GetRange godoc
+// @Summary Get schedules in a date range
+// @Description Lists schedules and recurring occurrences starting in [from, to), grouped by day
+// @Tags schedules
+// @Produce json
+// @Param from query string true "RFC 3339 time or YYYY-MM-DD date"
+// @Param to query string true "RFC 3339 time, or YYYY-MM-DD date to include that whole day"
+// @Param tz query string false "IANA timezone, defaults to the profile timezone"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 200, max 1000)"
+// @Success 200 {object} dto.CalendarResponse
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/range [get]
+func (h *ScheduleHandler) GetRange(w http.ResponseWriter, r *http.Request) {
+	h.serveCalendar(w, r, func(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+		return dto.ParseRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), loc)
+	})
+}
+
+// GetDay godoc
+// @Summary Get a day's schedules
+// @Tags schedules
+// @Produce json
+// @Param date path string true "YYYY-MM-DD"
+// @Param tz query string false "IANA timezone, defaults to the profile timezone"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 200, max 1000)"
+// @Success 200 {object} dto.CalendarResponse
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Router /schedule/day/{date} [get]
+func (h *ScheduleHandler) GetDay(w http.ResponseWriter, r *http.Request) {
+	h.serveCalendar(w, r, func(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+		return dto.ParseDay(mux.Vars(r)["date"], loc)
+	})
+}
+
+// GetWeek godoc
+// @Summary Get an ISO week's schedules
+// @Tags schedules
+// @Produce json
+// @Param week path string true "ISO 8601 week, e.g. 2025-W32"
+// @Param tz query string false "IANA timezone, defaults to the profile timezone"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 200, max 1000)"
+// @Success 200 {object} dto.CalendarResponse
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Router /schedule/week/{week} [get]
+func (h *ScheduleHandler) GetWeek(w http.ResponseWriter, r *http.Request) {
+	h.serveCalendar(w, r, func(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+		return dto.ParseISOWeek(mux.Vars(r)["week"], loc)
+	})
+}
+
+// GetMonth godoc
+// @Summary Get a month's schedules
+// @Tags schedules
+// @Produce json
+// @Param month path string true "YYYY-MM"
+// @Param tz query string false "IANA timezone, defaults to the profile timezone"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Items per page (default 200, max 1000)"
+// @Success 200 {object} dto.CalendarResponse
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Router /schedule/month/{month} [get]
+func (h *ScheduleHandler) GetMonth(w http.ResponseWriter, r *http.Request) {
+	h.serveCalendar(w, r, func(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+		return dto.ParseMonth(mux.Vars(r)["month"], loc)
+	})
+}
+
+// serveCalendar answers a calendar view with one page of the schedules in
+// the parsed range, grouped by day. The is_done, repeat_type and search
+// filters apply as in GetAll.
+func (h *ScheduleHandler) serveCalendar(w http.ResponseWriter, r *http.Request, parse rangeParser) {
+	ctx, cancel := withTimeout(r, 10*time.Second)
+	defer cancel()
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50022)
+		return
+	}
+
+	from, to, err := parse(r, loc)
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40019)
+		return
+	}
+
+	limit := defaultCalendarLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			httphelper.Error(w, r, http.StatusBadRequest, "limit must be a positive number", 40019)
+			return
+		}
+		if limit > maxCalendarLimit {
+			limit = maxCalendarLimit
+		}
+	}
+
+	filter := parseScheduleFilter(r)
+	filter.StartAfter = &from
+	filter.StartBefore = &to
+
+	schedules, next, err := h.Usecase.GetRange(ctx, userIDFromRequest(r), filter, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40020)
+			return
+		}
+		log.Printf("[Calendar] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch schedules", 50022)
+		return
+	}
+
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched schedules",
+		dto.ToCalendarResponse(from, to, loc, schedules, next))
+}
+
+// GetConflicts godoc
+// @Summary List overlapping schedules
+// @Description Lists every pair of schedules and recurring occurrences in [from, to) whose times overlap
+// @Tags schedules
+// @Produce json
+// @Param from query string true "RFC 3339 time or YYYY-MM-DD date"
+// @Param to query string true "RFC 3339 time, or YYYY-MM-DD date to include that whole day"
+// @Param tz query string false "IANA timezone, defaults to the profile timezone"
+// @Success 200 {array} dto.ConflictDTO
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/conflicts [get]
+func (h *ScheduleHandler) GetConflicts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 10*time.Second)
+	defer cancel()
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50023)
+		return
+	}
+
+	from, to, err := dto.ParseRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), loc)
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40019)
+		return
+	}
+
+	conflicts, err := h.Usecase.GetConflicts(ctx, userIDFromRequest(r), from, to)
+	if err != nil {
+		log.Printf("[Conflicts] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch conflicts", 50023)
+		return
+	}
+	for i := range conflicts {
+		conflicts[i].Start = conflicts[i].Start.In(loc)
+		conflicts[i].End = conflicts[i].End.In(loc)
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched conflicts", dto.ToConflictDTOs(conflicts))
+}
+
+// GetFreeSlots godoc
+// @Summary Find free time
+// @Description Lists the gaps between schedules in [from, to) that fall within the profile's waking hours
+// @Tags schedules
+// @Produce json
+// @Param from query string true "RFC 3339 time or YYYY-MM-DD date"
+// @Param to query string true "RFC 3339 time, or YYYY-MM-DD date to include that whole day"
+// @Param min_duration query string false "Shortest gap to list, in minutes or as a duration like 1h30m"
+// @Param tz query string false "IANA timezone, defaults to the profile timezone"
+// @Success 200 {array} dto.TimeSlotDTO
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/free-slots [get]
+func (h *ScheduleHandler) GetFreeSlots(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 10*time.Second)
+	defer cancel()
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50025)
+		return
+	}
+
+	from, to, err := dto.ParseRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), loc)
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40019)
+		return
+	}
+
+	minDuration, err := parseMinDuration(r.URL.Query().Get("min_duration"))
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40023)
+		return
+	}
+
+	slots, err := h.Usecase.GetFreeSlots(ctx, userIDFromRequest(r), from, to, minDuration)
+	if err != nil {
+		log.Printf("[FreeSlots] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to find free slots", 50025)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully found free slots", dto.ToTimeSlotDTOs(slots, loc))
+}
+
+// AutoPlace godoc
+// @Summary Schedule a task in free time
+// @Description Places a task in the best free slot before its deadline: the earliest for high priority, the tightest fit for normal and the latest for low. Deadlines more than 31 days away are searched up to 31 days ahead
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Only suggest the placement"
+// @Param body body dto.AutoPlaceRequest true "Task to place"
+// @Success 201 {object} dto.ScheduleResponseDTO
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Failure 409 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/auto-place [post]
+func (h *ScheduleHandler) AutoPlace(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 10*time.Second)
+	defer cancel()
+
+	var req dto.AutoPlaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40024)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40025)
+		return
+	}
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50026)
+		return
+	}
+
+	dryRun := strings.ToLower(r.URL.Query().Get("dry_run")) == "true"
+	placed, err := h.Usecase.AutoPlace(ctx, userIDFromRequest(r), req.ToDomain(), dryRun)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoFreeSlot) {
+			httphelper.Error(w, r, http.StatusConflict, err.Error(), 40903)
+			return
+		}
+		log.Printf("[AutoPlace] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to place task", 50026)
+		return
+	}
+
+	if dryRun {
+		httphelper.Success(w, r, http.StatusOK, "Found a slot for the task", dto.ToScheduleResponseDTO(placed.In(loc)))
+		return
+	}
+	httphelper.Success(w, r, http.StatusCreated, "Successfully scheduled task", dto.ToScheduleResponseDTO(placed.In(loc)))
+}
+
+// parseMinDuration reads a duration given in minutes or as a Go duration
+func parseMinDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if minutes, err := strconv.Atoi(value); err == nil && minutes >= 0 {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, errors.New("min_duration must be a number of minutes or a duration like 1h30m")
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"io"
 	"log"
+	"murim-helper/internal/domain"
 	"murim-helper/internal/dto"
 	"murim-helper/internal/usecase"
 	"murim-helper/pkg/httphelper"
@@ -14,8 +16,6 @@ import (
 	"strings"
 	"time"
 
-	httpSwagger "github.com/swaggo/http-swagger"
-
 	"github.com/gorilla/mux"
 )
 
@@ -26,14 +26,28 @@ type ScheduleHandler struct {
 func NewScheduleHandler(r *mux.Router, uc usecase.ScheduleUsecase) {
 	handler := &ScheduleHandler{Usecase: uc}
 
-	r.PathPrefix("/docs/").Handler(httpSwagger.WrapHandler)
 	s := r.PathPrefix("/schedule").Subrouter()
 	s.HandleFunc("", handler.Generate).Methods("POST")
 	s.HandleFunc("", handler.GetAll).Methods("GET")
 	s.HandleFunc("", handler.DeleteAll).Methods("DELETE")
 
+	s.HandleFunc("/items", handler.Create).Methods("POST")
+	s.HandleFunc("/quick-add", handler.QuickAdd).Methods("POST")
+	s.HandleFunc("/preview", handler.Preview).Methods("POST")
+	s.HandleFunc("/preview/{token}/commit", handler.CommitPreview).Methods("POST")
+
 	s.HandleFunc("/today", handler.GetToday).Methods("GET")
 	s.HandleFunc("/this-week", handler.GetThisWeek).Methods("GET")
+	s.HandleFunc("/range", handler.GetRange).Methods("GET")
+	s.HandleFunc("/day/{date}", handler.GetDay).Methods("GET")
+	s.HandleFunc("/day/{date}/replan", handler.Replan).Methods("POST")
+	s.HandleFunc("/week/{week}", handler.GetWeek).Methods("GET")
+	s.HandleFunc("/month/{month}", handler.GetMonth).Methods("GET")
+	s.HandleFunc("/conflicts", handler.GetConflicts).Methods("GET")
+	s.HandleFunc("/free-slots", handler.GetFreeSlots).Methods("GET")
+	s.HandleFunc("/auto-place", handler.AutoPlace).Methods("POST")
+	s.HandleFunc("/replan/{token}/accept", handler.AcceptReplan).Methods("POST")
+	s.HandleFunc("/replan/{token}", handler.RejectReplan).Methods("DELETE")
 
 	s.HandleFunc("/{id}", handler.Update).Methods("PUT")
 	s.HandleFunc("/{id}", handler.GetByID).Methods("GET")
@@ -66,6 +80,53 @@ func parsePagination(r *http.Request) (page, limit int) {
 	return
 }
 
+// requestLocation resolves the timezone to answer in from the tz query
+// param, the X-Timezone header or the user's profile
+func (h *ScheduleHandler) requestLocation(ctx context.Context, r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		tz = r.Header.Get("X-Timezone")
+	}
+	return h.Usecase.ResolveLocation(ctx, userIDFromRequest(r), tz)
+}
+
+// writeLocationError reports a failed requestLocation, using code for
+// anything but an invalid timezone
+func writeLocationError(w http.ResponseWriter, r *http.Request, err error, code int) {
+	if errors.Is(err, domain.ErrInvalidTimezone) {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40018)
+		return
+	}
+	log.Printf("[Timezone] error: %v", err)
+	httphelper.Error(w, r, http.StatusInternalServerError, "Failed to resolve timezone", code)
+}
+
+// inLocation converts the schedules' timestamps to loc
+func inLocation(schedules []domain.Schedule, loc *time.Location) []domain.Schedule {
+	for i := range schedules {
+		schedules[i] = schedules[i].In(loc)
+	}
+	return schedules
+}
+
+// parseStrict reads the strict flag, which rejects changes that overlap
+// existing schedules instead of warning about them
+func parseStrict(r *http.Request) bool {
+	return strings.ToLower(r.URL.Query().Get("strict")) == "true"
+}
+
+// writeConflictError answers a change rejected by strict mode and reports
+// whether err was one
+func writeConflictError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var conflictErr *domain.ConflictError
+	if !errors.As(err, &conflictErr) {
+		return false
+	}
+	httphelper.ErrorWithMeta(w, r, http.StatusConflict, "Schedule overlaps existing schedules", 40902,
+		dto.ConflictMeta{Conflicts: dto.ToConflictDTOs(conflictErr.Conflicts)})
+	return true
+}
+
 func parseScheduleFilter(r *http.Request) dto.ScheduleFilter {
 	var isDonePtr *bool
 	if isDoneStr := r.URL.Query().Get("is_done"); isDoneStr != "" {
@@ -125,7 +186,11 @@ func parseScheduleFilter(r *http.Request) dto.ScheduleFilter {
 // @Param search query string false "Search in title/description"
 // @Param sort_by query string false "Sort by field (start_time, end_time, created_at, title)"
 // @Param order query string false "Sort order (asc, desc)"
+// @Param start_after query string false "Only schedules starting after this time"
+// @Param start_before query string false "Only schedules starting before this time. With start_after, recurring series are expanded into occurrences and the two may be at most 366 days apart"
+// @Param tz query string false "IANA timezone of the returned times, defaults to the profile timezone"
 // @Success 200 {object} dto.PaginatedResponse
+// @Failure 400 {object} httphelper.ErrorResponse
 // @Failure 500 {object} httphelper.ErrorResponse
 // @Router /schedule [get]
 func (h *ScheduleHandler) GetAll(w http.ResponseWriter, r *http.Request) {
@@ -135,14 +200,24 @@ func (h *ScheduleHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	page, limit := parsePagination(r)
 	filter := parseScheduleFilter(r)
 
-	schedules, total, err := h.Usecase.GetAllSchedules(ctx, page, limit, filter)
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50001)
+		return
+	}
+
+	schedules, total, err := h.Usecase.GetAllSchedules(ctx, userIDFromRequest(r), page, limit, filter)
 	if err != nil {
+		if errors.Is(err, domain.ErrRangeTooLarge) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40031)
+			return
+		}
 		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch schedules", 50001)
 		return
 	}
 
 	response := dto.PaginatedResponse{
-		Data:       dto.ToScheduleResponseDTOs(schedules),
+		Data:       dto.ToScheduleResponseDTOs(inLocation(schedules, loc)),
 		Page:       page,
 		Limit:      limit,
 		TotalItems: total,
@@ -153,6 +228,11 @@ func (h *ScheduleHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ScheduleHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	if strings.ToLower(r.URL.Query().Get("dry_run")) == "true" {
+		h.Preview(w, r)
+		return
+	}
+
 	ctx, cancel := withTimeout(r, 15*time.Second) // longer for AI
 	defer cancel()
 
@@ -167,13 +247,185 @@ func (h *ScheduleHandler) Generate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.Usecase.GenerateSchedule(ctx, req.Description)
+	result, err := h.Usecase.GenerateSchedule(ctx, userIDFromRequest(r), req.Description, parseStrict(r))
 	if err != nil {
+		if writeConflictError(w, r, err) {
+			return
+		}
 		log.Printf("[Generate] error: %v", err)
 		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to generate schedule", 50002)
 		return
 	}
-	httphelper.Success(w, r, http.StatusCreated, "Successfully generated schedule", dto.ToScheduleResponseDTOs(result))
+	httphelper.SuccessWithMeta(w, r, http.StatusCreated, "Successfully generated schedule",
+		dto.ToScheduleResponseDTOs(result.Schedules), dto.ToGenerationMeta(*result))
+}
+
+// Create godoc
+// @Summary Create schedules manually
+// @Description Creates one schedule from an object, or several from an array, without going through the AI
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param strict query bool false "Reject schedules that overlap existing ones"
+// @Param body body dto.CreateScheduleRequest true "Schedule, or an array of schedules"
+// @Success 201 {object} dto.ScheduleResponseDTO
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Failure 409 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/items [post]
+func (h *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	var req dto.CreateSchedulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40021)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40022)
+		return
+	}
+
+	created, conflicts, err := h.Usecase.CreateSchedules(ctx, userIDFromRequest(r), req.ToDomain(), parseStrict(r))
+	if err != nil {
+		if writeConflictError(w, r, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidRecurrence) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40016)
+			return
+		}
+		log.Printf("[Create] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to create schedules", 50024)
+		return
+	}
+
+	meta := dto.ConflictMeta{Conflicts: dto.ToConflictDTOs(conflicts)}
+	if !req.Bulk {
+		httphelper.SuccessWithMeta(w, r, http.StatusCreated, "Successfully created schedule", dto.ToScheduleResponseDTO(created[0]), meta)
+		return
+	}
+	httphelper.SuccessWithMeta(w, r, http.StatusCreated, "Successfully created schedules", dto.ToScheduleResponseDTOs(created), meta)
+}
+
+// QuickAdd godoc
+// @Summary Add one schedule from plain text
+// @Description Reads text like "lunch with Budi tomorrow 12:30 for 1h every Friday" into a single schedule. Common phrasings are parsed offline; the AI is only used for the rest.
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param strict query bool false "Reject a schedule that overlaps existing ones"
+// @Param dry_run query bool false "Only return the parsed schedule"
+// @Param tz query string false "IANA timezone the text is meant in, defaults to the profile timezone"
+// @Param body body dto.QuickAddRequest true "Text to add"
+// @Success 201 {object} dto.ScheduleResponseDTO
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Failure 409 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/quick-add [post]
+func (h *ScheduleHandler) QuickAdd(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 15*time.Second) // longer for AI
+	defer cancel()
+
+	var req dto.QuickAddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40028)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40029)
+		return
+	}
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50030)
+		return
+	}
+
+	dryRun := strings.ToLower(r.URL.Query().Get("dry_run")) == "true"
+	result, err := h.Usecase.QuickAdd(ctx, userIDFromRequest(r), req.Text, loc, parseStrict(r), dryRun)
+	if err != nil {
+		if writeConflictError(w, r, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidRecurrence) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40016)
+			return
+		}
+		log.Printf("[QuickAdd] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to add schedule", 50030)
+		return
+	}
+
+	data := dto.ToScheduleResponseDTO(result.Schedules[0].In(loc))
+	if dryRun {
+		httphelper.SuccessWithMeta(w, r, http.StatusOK, "Successfully read schedule", data, dto.ToGenerationMeta(*result))
+		return
+	}
+	httphelper.SuccessWithMeta(w, r, http.StatusCreated, "Successfully added schedule", data, dto.ToGenerationMeta(*result))
+}
+
+func (h *ScheduleHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 15*time.Second) // longer for AI
+	defer cancel()
+
+	var req dto.GenerateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40001)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40002)
+		return
+	}
+
+	preview, err := h.Usecase.PreviewSchedule(ctx, userIDFromRequest(r), req.Description)
+	if err != nil {
+		log.Printf("[Preview] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to generate schedule preview", 50011)
+		return
+	}
+	httphelper.SuccessWithMeta(w, r, http.StatusOK, "Successfully generated schedule preview",
+		dto.ToSchedulePreviewResponse(*preview), dto.GenerationMeta{Provider: preview.Provider, FailedAttempts: preview.Attempts})
+}
+
+func (h *ScheduleHandler) CommitPreview(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	token := mux.Vars(r)["token"]
+
+	var req dto.CommitPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40009)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40010)
+		return
+	}
+
+	result, conflicts, err := h.Usecase.CommitPreview(ctx, userIDFromRequest(r), token, req.ToDomain(), parseStrict(r))
+	if err != nil {
+		if writeConflictError(w, r, err) {
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			httphelper.Error(w, r, http.StatusNotFound, "Preview not found, expired or already committed", 40405)
+			return
+		}
+		log.Printf("[CommitPreview] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to commit schedule preview", 50012)
+		return
+	}
+	httphelper.SuccessWithMeta(w, r, http.StatusCreated, "Successfully committed schedule preview",
+		dto.ToScheduleResponseDTOs(result), dto.ConflictMeta{Conflicts: dto.ToConflictDTOs(conflicts)})
 }
 
 func (h *ScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -197,7 +449,13 @@ func (h *ScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := h.Usecase.GetScheduleByID(ctx, id)
+	scope, err := domain.ParseEditScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40017)
+		return
+	}
+
+	existing, err := h.Usecase.GetScheduleByID(ctx, userIDFromRequest(r), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			httphelper.Error(w, r, http.StatusNotFound, "Schedule not found", 40402)
@@ -208,12 +466,29 @@ func (h *ScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	updated := req.ToDomain(*existing)
-	if err := h.Usecase.UpdateSchedule(ctx, id, updated); err != nil {
+	conflicts, err := h.Usecase.UpdateSchedule(ctx, userIDFromRequest(r), id, scope, updated, parseStrict(r))
+	if err != nil {
+		if writeConflictError(w, r, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidRecurrence) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40016)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidScope) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40017)
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			httphelper.Error(w, r, http.StatusNotFound, "Schedule not found", 40402)
+			return
+		}
 		log.Printf("[Update] error: %v", err)
 		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to update schedule", 50004)
 		return
 	}
-	httphelper.Success(w, r, http.StatusOK, "Successfully updated schedule", nil)
+	httphelper.SuccessWithMeta(w, r, http.StatusOK, "Successfully updated schedule", nil,
+		dto.ConflictMeta{Conflicts: dto.ToConflictDTOs(conflicts)})
 }
 
 func (h *ScheduleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -226,7 +501,7 @@ func (h *ScheduleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.Usecase.GetScheduleByID(ctx, id)
+	result, err := h.Usecase.GetScheduleByID(ctx, userIDFromRequest(r), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			httphelper.Error(w, r, http.StatusNotFound, "Schedule not found", 40401)
@@ -235,7 +510,13 @@ func (h *ScheduleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch schedule", 50005)
 		return
 	}
-	response := dto.ToScheduleResponseDTO(*result)
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50005)
+		return
+	}
+	response := dto.ToScheduleResponseDTO(result.In(loc))
 	httphelper.Success(w, r, http.StatusOK, "Successfully fetched schedule", response)
 }
 
@@ -243,10 +524,15 @@ func (h *ScheduleHandler) GetToday(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := withTimeout(r, 5*time.Second)
 	defer cancel()
 
-	loc := time.FixedZone("Asia/Jakarta", 7*3600)
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50006)
+		return
+	}
+	// time.Date normalizes the day after, so DST days are 23 or 25 hours long
 	now := time.Now().In(loc)
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
 
 	filter := dto.ScheduleFilter{
 		StartAfter:  &startOfDay,
@@ -258,28 +544,31 @@ func (h *ScheduleHandler) GetToday(w http.ResponseWriter, r *http.Request) {
 		filter.SortOrder = "asc"
 	}
 
-	schedules, _, err := h.Usecase.GetAllSchedules(ctx, 1, 100, filter)
+	schedules, _, err := h.Usecase.GetAllSchedules(ctx, userIDFromRequest(r), 1, maxCalendarLimit, filter)
 	if err != nil {
 		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch today's schedules", 50006)
 		return
 	}
 
-	httphelper.Success(w, r, http.StatusOK, "Successfully fetched today's schedule", dto.ToScheduleResponseDTOs(schedules))
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched today's schedule", dto.ToScheduleResponseDTOs(inLocation(schedules, loc)))
 }
 
 func (h *ScheduleHandler) GetThisWeek(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := withTimeout(r, 5*time.Second)
 	defer cancel()
 
-	loc := time.FixedZone("Asia/Jakarta", 7*3600)
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50007)
+		return
+	}
 	now := time.Now().In(loc)
 
 	weekday := int(now.Weekday())
 	if weekday == 0 {
 		weekday = 7
 	}
-	monday := now.AddDate(0, 0, -weekday+1)
-	startOfWeek := time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, loc)
+	startOfWeek := time.Date(now.Year(), now.Month(), now.Day()-weekday+1, 0, 0, 0, 0, loc)
 	endOfWeek := startOfWeek.AddDate(0, 0, 7)
 
 	filter := dto.ScheduleFilter{
@@ -292,13 +581,13 @@ func (h *ScheduleHandler) GetThisWeek(w http.ResponseWriter, r *http.Request) {
 		filter.SortOrder = "asc"
 	}
 
-	schedules, _, err := h.Usecase.GetAllSchedules(ctx, 1, 500, filter)
+	schedules, _, err := h.Usecase.GetAllSchedules(ctx, userIDFromRequest(r), 1, maxCalendarLimit, filter)
 	if err != nil {
 		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch this week's schedules", 50007)
 		return
 	}
 
-	httphelper.Success(w, r, http.StatusOK, "Successfully fetched this week's schedules", dto.ToScheduleResponseDTOs(schedules))
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched this week's schedules", dto.ToScheduleResponseDTOs(inLocation(schedules, loc)))
 }
 
 func (h *ScheduleHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
@@ -311,8 +600,18 @@ func (h *ScheduleHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.Usecase.DeleteScheduleByID(ctx, id)
+	scope, err := domain.ParseEditScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40017)
+		return
+	}
+
+	err = h.Usecase.DeleteScheduleByID(ctx, userIDFromRequest(r), id, scope)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidScope) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40017)
+			return
+		}
 		if errors.Is(err, sql.ErrNoRows) {
 			httphelper.Error(w, r, http.StatusNotFound, "Schedule not found", 40403)
 			return
@@ -341,14 +640,27 @@ func (h *ScheduleHandler) setDoneStatus(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	var err error
+	scope, err := domain.ParseEditScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40017)
+		return
+	}
+
 	if done {
-		err = h.Usecase.MarkScheduleAsDone(ctx, id)
+		err = h.Usecase.MarkScheduleAsDone(ctx, userIDFromRequest(r), id, scope)
 	} else {
-		err = h.Usecase.MarkScheduleAsUndone(ctx, id)
+		err = h.Usecase.MarkScheduleAsUndone(ctx, userIDFromRequest(r), id, scope)
 	}
 
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidScope) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40017)
+			return
+		}
+		if errors.Is(err, domain.ErrRangeTooLarge) {
+			httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40030)
+			return
+		}
 		if errors.Is(err, sql.ErrNoRows) {
 			httphelper.Error(w, r, http.StatusNotFound, "Schedule not found", 40404)
 			return
@@ -368,7 +680,7 @@ func (h *ScheduleHandler) DeleteAll(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := withTimeout(r, 5*time.Second)
 	defer cancel()
 
-	err := h.Usecase.DeleteAll(ctx)
+	err := h.Usecase.DeleteAll(ctx, userIDFromRequest(r))
 	if err != nil {
 		log.Printf("[DeleteAll] error: %v", err)
 		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to delete all schedules", 50010)
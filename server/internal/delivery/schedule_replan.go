@@ -0,0 +1,127 @@
+package delivery
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"murim-helper/pkg/httphelper"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Replan godoc
+// @Summary Ask the AI to rearrange a day
+// @Description Sends the day's schedules and the instruction to the AI and returns the proposed moves, deletions and additions without applying them
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param date path string true "YYYY-MM-DD"
+// @Param tz query string false "IANA timezone, defaults to the profile timezone"
+// @Param body body dto.ReplanRequest true "What to change"
+// @Success 200 {object} dto.ReplanResponse
+// @Failure 400 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/day/{date}/replan [post]
+func (h *ScheduleHandler) Replan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 15*time.Second) // longer for AI
+	defer cancel()
+
+	var req dto.ReplanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40026)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40027)
+		return
+	}
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50027)
+		return
+	}
+
+	day, _, err := dto.ParseDay(mux.Vars(r)["date"], loc)
+	if err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40019)
+		return
+	}
+
+	proposal, err := h.Usecase.ReplanDay(ctx, userIDFromRequest(r), day, req.Instruction)
+	if err != nil {
+		log.Printf("[Replan] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to replan day", 50027)
+		return
+	}
+	httphelper.SuccessWithMeta(w, r, http.StatusOK, "Successfully proposed changes to the day",
+		dto.ToReplanResponse(*proposal, loc), dto.GenerationMeta{Provider: proposal.Provider, FailedAttempts: proposal.Attempts})
+}
+
+// AcceptReplan godoc
+// @Summary Apply a proposed replan
+// @Description Applies the moves, deletions and additions of a replan. Fails with 409 when the day changed after it was proposed.
+// @Tags schedules
+// @Produce json
+// @Param token path string true "Replan token"
+// @Success 200 {object} dto.ReplanResponse
+// @Failure 404 {object} httphelper.ErrorResponse
+// @Failure 409 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/replan/{token}/accept [post]
+func (h *ScheduleHandler) AcceptReplan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 10*time.Second)
+	defer cancel()
+
+	loc, err := h.requestLocation(ctx, r)
+	if err != nil {
+		writeLocationError(w, r, err, 50028)
+		return
+	}
+
+	proposal, err := h.Usecase.AcceptReplan(ctx, userIDFromRequest(r), mux.Vars(r)["token"])
+	if err != nil {
+		if errors.Is(err, domain.ErrStaleReplan) {
+			httphelper.Error(w, r, http.StatusConflict, err.Error(), 40904)
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			httphelper.Error(w, r, http.StatusNotFound, "Replan not found, expired or already handled", 40408)
+			return
+		}
+		log.Printf("[AcceptReplan] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to apply replan", 50028)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully applied replan", dto.ToReplanResponse(*proposal, loc))
+}
+
+// RejectReplan godoc
+// @Summary Discard a proposed replan
+// @Tags schedules
+// @Produce json
+// @Param token path string true "Replan token"
+// @Failure 404 {object} httphelper.ErrorResponse
+// @Failure 500 {object} httphelper.ErrorResponse
+// @Router /schedule/replan/{token} [delete]
+func (h *ScheduleHandler) RejectReplan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	if err := h.Usecase.RejectReplan(ctx, userIDFromRequest(r), mux.Vars(r)["token"]); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httphelper.Error(w, r, http.StatusNotFound, "Replan not found, expired or already handled", 40408)
+			return
+		}
+		log.Printf("[RejectReplan] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to reject replan", 50029)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully rejected replan", nil)
+}
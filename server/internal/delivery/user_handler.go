@@ -0,0 +1,119 @@
+package delivery
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"murim-helper/internal/usecase"
+	"murim-helper/pkg/httphelper"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type UserHandler struct {
+	Usecase usecase.UserUsecase
+	Auth    usecase.AuthUsecase
+}
+
+// NewUserHandler registers user creation on the admin router and the
+// current-user endpoint on the router that authenticates the caller.
+func NewUserHandler(admin, protected *mux.Router, uc usecase.UserUsecase, auth usecase.AuthUsecase) {
+	handler := &UserHandler{Usecase: uc, Auth: auth}
+
+	admin.HandleFunc("/users", handler.Create).Methods("POST")
+	admin.HandleFunc("/users/{id}/api-keys", handler.IssueKey).Methods("POST")
+	protected.HandleFunc("/users/me", handler.Me).Methods("GET")
+}
+
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	var req dto.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40013)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		httphelper.Error(w, r, http.StatusBadRequest, err.Error(), 40014)
+		return
+	}
+
+	user, err := h.Usecase.CreateUser(ctx, req.Name, req.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrEmailTaken) {
+			httphelper.Error(w, r, http.StatusConflict, "Email is already registered", 40901)
+			return
+		}
+		log.Printf("[CreateUser] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to create user", 50015)
+		return
+	}
+
+	// Hand out a first API key so the new user can authenticate right away
+	key, plaintext, err := h.Auth.IssueAPIKey(ctx, user.ID, "default")
+	if err != nil {
+		log.Printf("[CreateUser] issue key error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to issue API key", 50019)
+		return
+	}
+
+	httphelper.Success(w, r, http.StatusCreated, "Successfully created user", dto.CreateUserResponse{
+		User:   dto.ToUserResponse(*user),
+		APIKey: dto.ToIssuedAPIKeyResponse(*key, plaintext),
+	})
+}
+
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	user, err := h.Usecase.GetUser(ctx, userIDFromRequest(r))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httphelper.Error(w, r, http.StatusNotFound, "User not found", 40406)
+			return
+		}
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch user", 50016)
+		return
+	}
+	httphelper.Success(w, r, http.StatusOK, "Successfully fetched user", dto.ToUserResponse(*user))
+}
+
+// IssueKey lets an operator issue a key for any user, e.g. the default user
+// that owns data created before accounts existed
+func (h *UserHandler) IssueKey(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := withTimeout(r, 5*time.Second)
+	defer cancel()
+
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httphelper.Error(w, r, http.StatusBadRequest, "Invalid request body", 40015)
+		return
+	}
+
+	user, err := h.Usecase.GetUser(ctx, getIDParam(r))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httphelper.Error(w, r, http.StatusNotFound, "User not found", 40406)
+			return
+		}
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to fetch user", 50016)
+		return
+	}
+
+	key, plaintext, err := h.Auth.IssueAPIKey(ctx, user.ID, req.Name)
+	if err != nil {
+		log.Printf("[IssueKey] error: %v", err)
+		httphelper.Error(w, r, http.StatusInternalServerError, "Failed to issue API key", 50019)
+		return
+	}
+	httphelper.Success(w, r, http.StatusCreated, "Successfully issued API key", dto.ToIssuedAPIKeyResponse(*key, plaintext))
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrTokenExpired       = errors.New("token has expired")
+	ErrAPIKeyRevoked      = errors.New("api key has been revoked")
+	ErrForbidden          = errors.New("forbidden")
+	ErrTokensDisabled     = errors.New("bearer tokens are not configured")
+	ErrAPIKeyRequired     = errors.New("an api key is required")
+)
+
+// Principal is who a request is authenticated as. KeyID is the API key
+// behind the credential: the key itself, or the one a bearer token was
+// issued with, so revoking the key ends both.
+type Principal struct {
+	UserID string
+	KeyID  string
+	Bearer bool
+}
+
+// APIKey is a long-lived credential. Only its SHA-256 hash is stored; the
+// plaintext is shown once when the key is issued.
+type APIKey struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrScheduleConflict = errors.New("schedule conflict")
+
+// Conflict is a pair of schedules, or occurrences of recurring ones, whose
+// times overlap. Start and End bound the overlapping part.
+type Conflict struct {
+	ScheduleID       string
+	Title            string
+	ConflictingID    string
+	ConflictingTitle string
+	Start            time.Time
+	End              time.Time
+}
+
+// ConflictError rejects a change that would overlap existing schedules
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	titles := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		titles = append(titles, fmt.Sprintf("%q overlaps %q", c.Title, c.ConflictingTitle))
+	}
+	return fmt.Sprintf("%s: %s", ErrScheduleConflict, strings.Join(titles, ", "))
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrScheduleConflict
+}
+
+// Overlaps reports whether two schedules share any time. Back-to-back
+// schedules, like 09:00-10:00 and 10:00-11:00, do not overlap.
+func (s Schedule) Overlaps(o Schedule) bool {
+	return s.StartTime.Before(o.EndTime) && o.StartTime.Before(s.EndTime)
+}
+
+// FindConflicts returns every overlapping pair of schedules for which keep
+// returns true, ordered by the start of the later schedule. A nil keep
+// keeps every pair.
+func FindConflicts(schedules []Schedule, keep func(a, b Schedule) bool) []Conflict {
+	sorted := make([]Schedule, len(schedules))
+	copy(sorted, schedules)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartTime.Before(sorted[j].StartTime) })
+
+	var conflicts []Conflict
+	var active []Schedule
+	for _, s := range sorted {
+		// Drop schedules that ended before this one starts
+		n := 0
+		for _, a := range active {
+			if a.EndTime.After(s.StartTime) {
+				active[n] = a
+				n++
+			}
+		}
+		active = active[:n]
+
+		for _, a := range active {
+			if !a.Overlaps(s) || (keep != nil && !keep(a, s)) {
+				continue
+			}
+			conflicts = append(conflicts, newConflict(a, s))
+		}
+		active = append(active, s)
+	}
+	return conflicts
+}
+
+func newConflict(a, b Schedule) Conflict {
+	c := Conflict{
+		ScheduleID:       a.ID,
+		Title:            a.Title,
+		ConflictingID:    b.ID,
+		ConflictingTitle: b.Title,
+		Start:            a.StartTime,
+		End:              a.EndTime,
+	}
+	if b.StartTime.After(c.Start) {
+		c.Start = b.StartTime
+	}
+	if b.EndTime.Before(c.End) {
+		c.End = b.EndTime
+	}
+	return c
+}
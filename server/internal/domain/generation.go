@@ -0,0 +1,15 @@
+package domain
+
+// ProviderAttempt records a single AI provider that failed to produce a schedule.
+type ProviderAttempt struct {
+	Provider string `json:"provider"`
+	Error    string `json:"error"`
+}
+
+// GenerationResult is the outcome of running the AI provider chain.
+type GenerationResult struct {
+	Schedules []Schedule
+	Provider  string            // provider that produced Schedules
+	Attempts  []ProviderAttempt // providers that failed before it, in order
+	Conflicts []Conflict        // existing schedules the generated ones overlap
+}
@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// occurrenceIDLayout is the UTC timestamp suffix of an occurrence ID
+const occurrenceIDLayout = "20060102T150405Z"
+
+// ScheduleException overrides, completes or cancels a single occurrence of a
+// recurring series. OccurrenceStart is the start the rule generated, so the
+// exception keeps matching even when StartTime moves the occurrence.
+type ScheduleException struct {
+	ScheduleID      string     `db:"schedule_id"`
+	OccurrenceStart time.Time  `db:"occurrence_start"`
+	Title           *string    `db:"title"`
+	Description     *string    `db:"description"`
+	StartTime       *time.Time `db:"start_time"`
+	EndTime         *time.Time `db:"end_time"`
+	IsDone          bool       `db:"is_done"`
+	Cancelled       bool       `db:"cancelled"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+}
+
+// OccurrenceID identifies one occurrence of a recurring schedule, e.g.
+// "<schedule id>_20250805T000000Z"
+func OccurrenceID(scheduleID string, start time.Time) string {
+	return scheduleID + "_" + start.UTC().Format(occurrenceIDLayout)
+}
+
+// ParseOccurrenceID splits an occurrence ID into the ID of the recurring
+// schedule and the original start. ok is false for plain schedule IDs.
+func ParseOccurrenceID(id string) (scheduleID string, start time.Time, ok bool) {
+	i := strings.LastIndex(id, "_")
+	if i <= 0 {
+		return "", time.Time{}, false
+	}
+	start, err := time.Parse(occurrenceIDLayout, id[i+1:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return id[:i], start, true
+}
+
+// IsOccurrence reports whether the series' rule generates an occurrence
+// starting at start.
+func (s Schedule) IsOccurrence(start time.Time) bool {
+	rule, err := s.Recurrence()
+	if err != nil || rule == nil {
+		return false
+	}
+	next := rule.After(s.LocalStart(), start.Add(-time.Second))
+	return next != nil && next.Equal(start)
+}
+
+// Occurrence builds the occurrence of the series that the rule generated at
+// start, with exc applied when it is not nil.
+func (s Schedule) Occurrence(start time.Time, exc *ScheduleException) Schedule {
+	start = start.In(s.Location())
+	occ := s
+	occ.ID = OccurrenceID(s.ID, start)
+	occ.OccurrenceStart = &start
+	occ.StartTime = start
+	occ.EndTime = start.Add(s.EndTime.Sub(s.StartTime))
+	occ.IsDone = false
+
+	if exc != nil {
+		if exc.Title != nil {
+			occ.Title = *exc.Title
+		}
+		if exc.Description != nil {
+			occ.Description = *exc.Description
+		}
+		if exc.StartTime != nil {
+			occ.StartTime = *exc.StartTime
+		}
+		if exc.EndTime != nil {
+			occ.EndTime = *exc.EndTime
+		}
+		occ.IsDone = exc.IsDone
+	}
+	return occ
+}
+
+// Occurrences expands the series into the occurrences that start in
+// [from, to), applying exceptions. Cancelled occurrences are left out, and
+// an occurrence moved into the window by an exception is included.
+func (s Schedule) Occurrences(from, to time.Time, exceptions []ScheduleException) ([]Schedule, error) {
+	rule, err := s.Recurrence()
+	if err != nil || rule == nil {
+		return nil, err
+	}
+
+	byStart := make(map[int64]*ScheduleException, len(exceptions))
+	for i := range exceptions {
+		byStart[exceptions[i].OccurrenceStart.Unix()] = &exceptions[i]
+	}
+
+	var occurrences []Schedule
+	seen := make(map[int64]bool)
+	inWindow := func(occ Schedule) bool {
+		return !occ.StartTime.Before(from) && occ.StartTime.Before(to)
+	}
+
+	for _, start := range rule.Between(s.LocalStart(), from, to) {
+		seen[start.Unix()] = true
+		exc := byStart[start.Unix()]
+		if exc != nil && exc.Cancelled {
+			continue
+		}
+		if occ := s.Occurrence(start, exc); inWindow(occ) {
+			occurrences = append(occurrences, occ)
+		}
+	}
+
+	for i := range exceptions {
+		exc := &exceptions[i]
+		if exc.Cancelled || exc.StartTime == nil || seen[exc.OccurrenceStart.Unix()] {
+			continue
+		}
+		if !s.IsOccurrence(exc.OccurrenceStart) {
+			continue
+		}
+		if occ := s.Occurrence(exc.OccurrenceStart, exc); inWindow(occ) {
+			occurrences = append(occurrences, occ)
+		}
+	}
+
+	return occurrences, nil
+}
+
+// SetOverrides records how updated differs from base, the occurrence as the
+// series generates it. Fields equal to the series are not stored, so later
+// edits to the series still reach this occurrence.
+func (e *ScheduleException) SetOverrides(base, updated Schedule) {
+	e.Title, e.Description, e.StartTime, e.EndTime = nil, nil, nil, nil
+	if updated.Title != base.Title {
+		e.Title = &updated.Title
+	}
+	if updated.Description != base.Description {
+		e.Description = &updated.Description
+	}
+	if !updated.StartTime.Equal(base.StartTime) {
+		e.StartTime = &updated.StartTime
+	}
+	if !updated.EndTime.Equal(base.EndTime) {
+		e.EndTime = &updated.EndTime
+	}
+	e.IsDone = updated.IsDone
+}
+
+// Shift moves the exception to the occurrence d later, together with any
+// overridden start and end, as when the whole series moves by d
+func (e *ScheduleException) Shift(d time.Duration) {
+	e.OccurrenceStart = e.OccurrenceStart.Add(d)
+	if e.StartTime != nil {
+		start := e.StartTime.Add(d)
+		e.StartTime = &start
+	}
+	if e.EndTime != nil {
+		end := e.EndTime.Add(d)
+		e.EndTime = &end
+	}
+}
+
+// EditScope selects which occurrences of a recurring series an edit touches
+type EditScope string
+
+const (
+	ScopeOccurrence EditScope = "occurrence"
+	ScopeFollowing  EditScope = "following"
+	ScopeSeries     EditScope = "series"
+)
+
+var ErrInvalidScope = errors.New("invalid scope")
+
+// ParseEditScope validates a scope; an empty value selects the default for
+// the ID being edited.
+func ParseEditScope(value string) (EditScope, error) {
+	switch scope := EditScope(strings.ToLower(strings.TrimSpace(value))); scope {
+	case "", ScopeOccurrence, ScopeFollowing, ScopeSeries:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("%w %q, use occurrence, following or series", ErrInvalidScope, value)
+	}
+}
+
+// ExceptionMove re-keys the exceptions of FromID starting at or after Since
+// onto ToID, shifting their occurrence start and overridden times by Shift.
+type ExceptionMove struct {
+	FromID string
+	ToID   string
+	Since  time.Time
+	Shift  time.Duration
+}
+
+// SeriesChange is an edit to the rows of one series that must be saved
+// atomically. Exceptions are created or replaced like SaveExceptions does.
+type SeriesChange struct {
+	Update     []Schedule
+	Insert     []Schedule
+	Delete     []string
+	Move       []ExceptionMove
+	Exceptions []ScheduleException
+}
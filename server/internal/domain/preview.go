@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// SchedulePreview holds AI-generated schedules that have not been saved yet.
+// The token is handed to the client, which may commit it (optionally with
+// edits) before ExpiresAt.
+type SchedulePreview struct {
+	Token     string
+	UserID    string
+	Schedules []Schedule
+	Provider  string
+	Attempts  []ProviderAttempt
+	Conflicts []Conflict // found when the preview was generated, not stored
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
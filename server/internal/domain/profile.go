@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Habit is a recurring activity the AI must include in generated days.
+type Habit struct {
+	Title           string   `json:"title"`
+	Time            string   `json:"time,omitempty"`             // preferred "HH:MM", optional
+	DurationMinutes int      `json:"duration_minutes,omitempty"` // optional
+	Days            []string `json:"days,omitempty"`             // e.g. ["mon","wed"]; empty means every day
+}
+
+// TimeBlock is a period in which nothing may be scheduled.
+type TimeBlock struct {
+	Title string   `json:"title,omitempty"`
+	Start string   `json:"start"` // "HH:MM"
+	End   string   `json:"end"`   // "HH:MM"
+	Days  []string `json:"days,omitempty"`
+}
+
+// UserProfile describes the routine the AI prompt is built from.
+type UserProfile struct {
+	UserID       string      `json:"user_id"`
+	Occupation   string      `json:"occupation"`
+	WakeTime     string      `json:"wake_time"`  // "HH:MM"
+	SleepTime    string      `json:"sleep_time"` // "HH:MM"
+	Timezone     string      `json:"timezone"`   // IANA name, e.g. "Asia/Jakarta"
+	WorkStart    string      `json:"work_start,omitempty"`
+	WorkEnd      string      `json:"work_end,omitempty"`
+	Habits       []Habit     `json:"habits"`
+	BlockedTimes []TimeBlock `json:"blocked_times"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// DefaultUserProfile is the routine the prompts were originally written for,
+// used until a profile has been saved.
+func DefaultUserProfile() UserProfile {
+	return UserProfile{
+		Occupation: "software engineer",
+		WakeTime:   "07:00",
+		SleepTime:  "23:00",
+		Timezone:   "Asia/Jakarta",
+		WorkStart:  "09:00",
+		WorkEnd:    "17:00",
+		Habits: []Habit{
+			{Title: "Bible reading", Time: "07:00", DurationMinutes: 30},
+			{Title: "Gym", DurationMinutes: 60},
+			{Title: "Night prayer", Time: "22:30", DurationMinutes: 30},
+		},
+		BlockedTimes: []TimeBlock{},
+	}
+}
+
+var weekdayNames = map[string]bool{
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+}
+
+func (p UserProfile) Validate() error {
+	if err := validateClock("wake_time", p.WakeTime, true); err != nil {
+		return err
+	}
+	if err := validateClock("sleep_time", p.SleepTime, true); err != nil {
+		return err
+	}
+	if p.WakeTime == p.SleepTime {
+		return errors.New("wake_time and sleep_time must differ")
+	}
+	if _, err := LoadLocation(p.Timezone); err != nil || p.Timezone == "" {
+		return fmt.Errorf("timezone %q is not a valid IANA timezone", p.Timezone)
+	}
+	if (p.WorkStart == "") != (p.WorkEnd == "") {
+		return errors.New("work_start and work_end must be set together")
+	}
+	if err := validateClock("work_start", p.WorkStart, false); err != nil {
+		return err
+	}
+	if err := validateClock("work_end", p.WorkEnd, false); err != nil {
+		return err
+	}
+
+	for i, h := range p.Habits {
+		if strings.TrimSpace(h.Title) == "" {
+			return fmt.Errorf("habits[%d]: title is required", i)
+		}
+		if err := validateClock(fmt.Sprintf("habits[%d].time", i), h.Time, false); err != nil {
+			return err
+		}
+		if h.DurationMinutes < 0 {
+			return fmt.Errorf("habits[%d]: duration_minutes cannot be negative", i)
+		}
+		if err := validateDays(fmt.Sprintf("habits[%d].days", i), h.Days); err != nil {
+			return err
+		}
+	}
+
+	for i, b := range p.BlockedTimes {
+		if err := validateClock(fmt.Sprintf("blocked_times[%d].start", i), b.Start, true); err != nil {
+			return err
+		}
+		if err := validateClock(fmt.Sprintf("blocked_times[%d].end", i), b.End, true); err != nil {
+			return err
+		}
+		if err := validateDays(fmt.Sprintf("blocked_times[%d].days", i), b.Days); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Location returns the profile timezone, falling back to UTC.
+func (p UserProfile) Location() *time.Location {
+	loc, err := LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func validateClock(field, value string, required bool) error {
+	if value == "" {
+		if required {
+			return fmt.Errorf("%s is required", field)
+		}
+		return nil
+	}
+	if _, err := time.Parse("15:04", value); err != nil {
+		return fmt.Errorf("%s must be in HH:MM format", field)
+	}
+	return nil
+}
+
+func validateDays(field string, days []string) error {
+	for _, d := range days {
+		if !weekdayNames[strings.ToLower(d)] {
+			return fmt.Errorf("%s: unknown day %q (use mon..sun)", field, d)
+		}
+	}
+	return nil
+}
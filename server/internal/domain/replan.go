@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStaleReplan rejects accepting a replan whose schedules changed after it
+// was proposed
+var ErrStaleReplan = errors.New("schedules changed since the replan was proposed")
+
+// ReplanMove moves an existing schedule, or one occurrence of a recurring
+// one, to a new time
+type ReplanMove struct {
+	ScheduleID string    `json:"schedule_id"`
+	Title      string    `json:"title"`
+	FromStart  time.Time `json:"from_start"`
+	FromEnd    time.Time `json:"from_end"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+}
+
+// ReplanDeletion drops an existing schedule or occurrence from the day
+type ReplanDeletion struct {
+	ScheduleID string    `json:"schedule_id"`
+	Title      string    `json:"title"`
+	StartTime  time.Time `json:"start_time"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// ReplanDiff is the set of changes the AI proposes for a day
+type ReplanDiff struct {
+	Moves     []ReplanMove     `json:"moves"`
+	Deletions []ReplanDeletion `json:"deletions"`
+	Additions []Schedule       `json:"additions"`
+}
+
+// ReplanProposal holds a diff for a day until the user accepts or rejects
+// it, like a SchedulePreview does for a generated day.
+type ReplanProposal struct {
+	Token       string
+	UserID      string
+	Day         time.Time // midnight of the replanned day in the user's timezone
+	Instruction string
+	Diff        ReplanDiff
+	Provider    string
+	Attempts    []ProviderAttempt
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
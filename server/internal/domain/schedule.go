@@ -2,14 +2,30 @@ package domain
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"murim-helper/pkg/rrule"
+
 	"github.com/google/uuid"
 )
 
+// RepeatNone marks a one-off schedule
+const RepeatNone = "none"
+
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence")
+	ErrInvalidTimezone   = errors.New("invalid timezone")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrRangeTooLarge     = errors.New("range is too large")
+)
+
 type Schedule struct {
 	ID          string     `db:"id" json:"id"`
+	UserID      string     `db:"user_id" json:"user_id"`
 	Title       string     `db:"title" json:"title"`
 	Description string     `db:"description" json:"description"`
 	StartTime   time.Time  `db:"start_time" json:"start_time"`
@@ -18,6 +34,134 @@ type Schedule struct {
 	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
 	RepeatType  string     `db:"repeat_type" json:"repeat_type"`
 	RepeatUntil *time.Time `db:"repeat_until" json:"repeat_until,omitempty"`
+	// RRule is an RFC 5545 recurrence rule anchored at StartTime. RepeatType
+	// and RepeatUntil are derived from it for filtering.
+	RRule string `db:"rrule" json:"rrule,omitempty"`
+
+	// Timezone is the IANA zone the schedule was planned in. Recurrences
+	// keep its wall-clock time across DST changes.
+	Timezone string `db:"timezone" json:"timezone,omitempty"`
+	// SeriesID links the rows of one series. It equals ID unless the row was
+	// split off an older row by an edit of "this and following" occurrences.
+	SeriesID string `db:"series_id" json:"series_id,omitempty"`
+	// OccurrenceStart is only set on occurrences expanded from a recurring
+	// row; it is the start the rule generated.
+	OccurrenceStart *time.Time `db:"-" json:"occurrence_start,omitempty"`
+}
+
+// locations caches the zones LoadLocation has read, keyed by name
+var locations sync.Map
+
+// LoadLocation is time.LoadLocation without reading the zoneinfo file again
+// for a zone it already loaded. Expansion and slot search need the zone of
+// every occurrence.
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// Location returns the schedule's timezone, or UTC when it has none
+func (s Schedule) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LocalStart is StartTime in the schedule's timezone, the DTSTART its
+// recurrence rule is expanded from
+func (s Schedule) LocalStart() time.Time {
+	return s.StartTime.In(s.Location())
+}
+
+// In returns a copy with every timestamp expressed in loc
+func (s Schedule) In(loc *time.Location) Schedule {
+	s.StartTime = s.StartTime.In(loc)
+	s.EndTime = s.EndTime.In(loc)
+	s.CreatedAt = s.CreatedAt.In(loc)
+	if s.RepeatUntil != nil {
+		t := s.RepeatUntil.In(loc)
+		s.RepeatUntil = &t
+	}
+	if s.OccurrenceStart != nil {
+		t := s.OccurrenceStart.In(loc)
+		s.OccurrenceStart = &t
+	}
+	return s
+}
+
+// NormalizeRecurrence validates the recurrence rule and derives RepeatType
+// (its frequency) and RepeatUntil (its last occurrence) from it. Without a
+// rule, the legacy repeat types "daily", "weekly", "monthly" and "yearly"
+// are converted to the equivalent rule bounded by RepeatUntil.
+func (s *Schedule) NormalizeRecurrence() error {
+	if strings.TrimSpace(s.RRule) == "" {
+		s.RRule = ""
+		switch repeatType := strings.ToLower(strings.TrimSpace(s.RepeatType)); repeatType {
+		case "", RepeatNone:
+			s.RepeatType = RepeatNone
+			s.RepeatUntil = nil
+			return nil
+		case "daily", "weekly", "monthly", "yearly":
+			s.RRule = "FREQ=" + strings.ToUpper(repeatType)
+			if s.RepeatUntil != nil {
+				s.RRule += ";UNTIL=" + s.RepeatUntil.UTC().Format("20060102T150405Z")
+			}
+		default:
+			return fmt.Errorf("%w: unsupported repeat_type %q, use rrule for custom recurrence", ErrInvalidRecurrence, s.RepeatType)
+		}
+	}
+
+	rule, err := rrule.Parse(s.RRule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	s.RRule = rule.String()
+	s.RepeatType = strings.ToLower(string(rule.Freq))
+	s.RepeatUntil = nil
+	if !s.StartTime.IsZero() {
+		s.RepeatUntil = rule.Last(s.LocalStart())
+	}
+	return nil
+}
+
+// SetRepeatUntil bounds the recurrence at until, or removes the bound when
+// until is nil, keeping the rest of the rule intact.
+func (s *Schedule) SetRepeatUntil(until *time.Time) error {
+	if s.RRule == "" {
+		s.RepeatUntil = until
+		return nil
+	}
+	rule, err := rrule.Parse(s.RRule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	rule.SetUntil(until)
+	s.RRule = rule.String()
+	return nil
+}
+
+// Recurrence returns the parsed rule, or nil for a one-off schedule
+func (s Schedule) Recurrence() (*rrule.Rule, error) {
+	if s.RRule == "" {
+		return nil, nil
+	}
+	rule, err := rrule.Parse(s.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return rule, nil
 }
 
 func ParseSchedulesFromJSON(jsonStr string) ([]Schedule, error) {
@@ -28,6 +172,7 @@ func ParseSchedulesFromJSON(jsonStr string) ([]Schedule, error) {
 		EndTime     string  `json:"end_time"`
 		RepeatType  string  `json:"repeat_type"`
 		RepeatUntil *string `json:"repeat_until"`
+		RRule       string  `json:"rrule"`
 	}
 
 	err := json.Unmarshal([]byte(jsonStr), &rawItems)
@@ -36,24 +181,29 @@ func ParseSchedulesFromJSON(jsonStr string) ([]Schedule, error) {
 	}
 
 	var schedules []Schedule
-	for _, item := range rawItems {
-		start, err1 := time.Parse(time.RFC3339, item.StartTime)
-		end, err2 := time.Parse(time.RFC3339, item.EndTime)
-		if err1 != nil || err2 != nil {
-			fmt.Printf("Error parsing times:\n  start: %v\n  end: %v\n", err1, err2)
-			continue
+	for i, item := range rawItems {
+		start, err := time.Parse(time.RFC3339, item.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: invalid start_time %q: %w", i, item.StartTime, err)
+		}
+		end, err := time.Parse(time.RFC3339, item.EndTime)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: invalid end_time %q: %w", i, item.EndTime, err)
 		}
 
 		var repeatUntil *time.Time
 		if item.RepeatUntil != nil && *item.RepeatUntil != "" {
 			t, err := time.Parse(time.RFC3339, *item.RepeatUntil)
-			if err == nil {
-				repeatUntil = &t
+			if err != nil {
+				return nil, fmt.Errorf("item %d: invalid repeat_until %q: %w", i, *item.RepeatUntil, err)
 			}
+			repeatUntil = &t
 		}
 
-		schedules = append(schedules, Schedule{
-			ID:          uuid.NewString(),
+		id := uuid.NewString()
+		schedule := Schedule{
+			ID:          id,
+			SeriesID:    id,
 			Title:       item.Title,
 			Description: item.Description,
 			StartTime:   start,
@@ -61,7 +211,12 @@ func ParseSchedulesFromJSON(jsonStr string) ([]Schedule, error) {
 			IsDone:      false,
 			RepeatType:  item.RepeatType,
 			RepeatUntil: repeatUntil,
-		})
+			RRule:       item.RRule,
+		}
+		if err := schedule.NormalizeRecurrence(); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		schedules = append(schedules, schedule)
 	}
 
 	return schedules, nil
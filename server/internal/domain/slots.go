@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNoFreeSlot = errors.New("no free slot")
+
+// Task priorities for auto-placement
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// TimeSlot is a free period between schedules
+type TimeSlot struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (t TimeSlot) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// PlacementTask is a task to be placed in the user's free time
+type PlacementTask struct {
+	Title       string
+	Description string
+	Duration    time.Duration
+	Earliest    time.Time  // do not start before
+	Deadline    *time.Time // must be finished by, optional
+	Priority    string
+}
+
+// FreeSlots returns the gaps of at least minDuration in [from, to) that
+// fall inside the profile's waking hours and outside its blocked times and
+// the busy schedules. Days are taken in the profile's timezone, and a sleep
+// time before the wake time means the user goes to bed after midnight.
+func FreeSlots(busy []Schedule, from, to time.Time, profile UserProfile, minDuration time.Duration) []TimeSlot {
+	loc := profile.Location()
+	var blocked []TimeSlot
+	for _, s := range busy {
+		blocked = append(blocked, TimeSlot{Start: s.StartTime, End: s.EndTime})
+	}
+
+	var awake []TimeSlot
+	first := from.In(loc)
+	// Start a day early, a late sleep time carries the previous day past midnight
+	for d := time.Date(first.Year(), first.Month(), first.Day()-1, 0, 0, 0, 0, loc); d.Before(to); d = d.AddDate(0, 0, 1) {
+		if wake, sleep, ok := dailyWindow(d, profile.WakeTime, profile.SleepTime); ok {
+			awake = append(awake, TimeSlot{Start: wake, End: sleep})
+		}
+		weekday := strings.ToLower(d.Weekday().String()[:3])
+		for _, b := range profile.BlockedTimes {
+			if !onDay(b.Days, weekday) {
+				continue
+			}
+			if start, end, ok := dailyWindow(d, b.Start, b.End); ok {
+				blocked = append(blocked, TimeSlot{Start: start, End: end})
+			}
+		}
+	}
+
+	var free []TimeSlot
+	for _, w := range awake {
+		if w.Start.Before(from) {
+			w.Start = from
+		}
+		if w.End.After(to) {
+			w.End = to
+		}
+		if !w.Start.Before(w.End) {
+			continue
+		}
+		for _, slot := range subtract(w, blocked) {
+			if slot.Duration() >= minDuration && slot.Duration() > 0 {
+				free = append(free, slot)
+			}
+		}
+	}
+	return free
+}
+
+// PickSlot chooses where a task of the given duration goes among slots:
+// high priority takes the earliest start, normal priority the smallest slot
+// it fits in (keeping long stretches free), and low priority the latest
+// start. ok is false when it fits nowhere.
+func PickSlot(slots []TimeSlot, duration time.Duration, priority string) (TimeSlot, bool) {
+	var best *TimeSlot
+	for i := range slots {
+		slot := slots[i]
+		if slot.Duration() < duration {
+			continue
+		}
+		if best == nil {
+			best = &slots[i]
+			continue
+		}
+		switch priority {
+		case PriorityHigh:
+			if slot.Start.Before(best.Start) {
+				best = &slots[i]
+			}
+		case PriorityLow:
+			if slot.End.After(best.End) {
+				best = &slots[i]
+			}
+		default:
+			if slot.Duration() < best.Duration() ||
+				(slot.Duration() == best.Duration() && slot.Start.Before(best.Start)) {
+				best = &slots[i]
+			}
+		}
+	}
+	if best == nil {
+		return TimeSlot{}, false
+	}
+	if priority == PriorityLow {
+		return TimeSlot{Start: best.End.Add(-duration), End: best.End}, true
+	}
+	return TimeSlot{Start: best.Start, End: best.Start.Add(duration)}, true
+}
+
+// dailyWindow resolves "HH:MM" start and end clocks on day d. An end at or
+// before the start falls on the next day.
+func dailyWindow(d time.Time, startClock, endClock string) (time.Time, time.Time, bool) {
+	start, err1 := time.Parse("15:04", startClock)
+	end, err2 := time.Parse("15:04", endClock)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	s := time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), 0, 0, d.Location())
+	e := time.Date(d.Year(), d.Month(), d.Day(), end.Hour(), end.Minute(), 0, 0, d.Location())
+	if !e.After(s) {
+		e = time.Date(d.Year(), d.Month(), d.Day()+1, end.Hour(), end.Minute(), 0, 0, d.Location())
+	}
+	return s, e, true
+}
+
+func onDay(days []string, weekday string) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if strings.ToLower(d) == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// subtract removes the busy periods from window
+func subtract(window TimeSlot, busy []TimeSlot) []TimeSlot {
+	var overlapping []TimeSlot
+	for _, b := range busy {
+		if b.Start.Before(window.End) && b.End.After(window.Start) {
+			overlapping = append(overlapping, b)
+		}
+	}
+	sort.Slice(overlapping, func(i, j int) bool { return overlapping[i].Start.Before(overlapping[j].Start) })
+
+	var free []TimeSlot
+	cursor := window.Start
+	for _, b := range overlapping {
+		if b.Start.After(cursor) {
+			free = append(free, TimeSlot{Start: cursor, End: b.Start})
+		}
+		if b.End.After(cursor) {
+			cursor = b.End
+		}
+	}
+	if cursor.Before(window.End) {
+		free = append(free, TimeSlot{Start: cursor, End: window.End})
+	}
+	return free
+}
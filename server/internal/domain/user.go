@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultUserID owns everything created before accounts existed.
+const DefaultUserID = "00000000-0000-0000-0000-000000000000"
+
+var ErrEmailTaken = errors.New("email is already registered")
+
+type User struct {
+	ID        string    `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
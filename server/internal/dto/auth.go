@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"murim-helper/internal/domain"
+	"time"
+)
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+}
+
+type APIKeyResponse struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Prefix     string  `json:"prefix"`
+	CreatedAt  string  `json:"created_at"`
+	LastUsedAt *string `json:"last_used_at,omitempty"`
+	RevokedAt  *string `json:"revoked_at,omitempty"`
+}
+
+// IssuedAPIKeyResponse includes the plaintext key, returned only once
+type IssuedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type TokenResponse struct {
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type CreateUserResponse struct {
+	User   UserResponse         `json:"user"`
+	APIKey IssuedAPIKeyResponse `json:"api_key"`
+}
+
+func ToAPIKeyResponse(k domain.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		CreatedAt:  k.CreatedAt.Format(time.RFC3339),
+		LastUsedAt: formatOptionalTime(k.LastUsedAt),
+		RevokedAt:  formatOptionalTime(k.RevokedAt),
+	}
+}
+
+func ToAPIKeyResponses(keys []domain.APIKey) []APIKeyResponse {
+	result := make([]APIKeyResponse, len(keys))
+	for i, k := range keys {
+		result[i] = ToAPIKeyResponse(k)
+	}
+	return result
+}
+
+func ToIssuedAPIKeyResponse(k domain.APIKey, plaintext string) IssuedAPIKeyResponse {
+	return IssuedAPIKeyResponse{APIKeyResponse: ToAPIKeyResponse(k), Key: plaintext}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	str := t.Format(time.RFC3339)
+	return &str
+}
@@ -0,0 +1,134 @@
+package dto
+
+import (
+	"fmt"
+	"murim-helper/internal/domain"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MaxCalendarRange bounds how far a single request may expand recurring
+// series
+const MaxCalendarRange = 366 * 24 * time.Hour
+
+const dateLayout = "2006-01-02"
+
+var isoWeekPattern = regexp.MustCompile(`^(\d{4})-?W(\d{2})$`)
+
+// CalendarDay holds the schedules starting on one day of the calendar
+type CalendarDay struct {
+	Date  string                `json:"date"` // YYYY-MM-DD in the response timezone
+	Items []ScheduleResponseDTO `json:"items"`
+}
+
+// CalendarResponse is one page of a calendar view. NextCursor is empty on
+// the last page.
+type CalendarResponse struct {
+	From       string        `json:"from"`
+	To         string        `json:"to"`
+	Timezone   string        `json:"timezone"`
+	Days       []CalendarDay `json:"days"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// ToCalendarResponse groups schedules, sorted by start time, by the day
+// they start on in loc. Days without schedules are left out.
+func ToCalendarResponse(from, to time.Time, loc *time.Location, schedules []domain.Schedule, nextCursor string) CalendarResponse {
+	resp := CalendarResponse{
+		From:       from.In(loc).Format(time.RFC3339),
+		To:         to.In(loc).Format(time.RFC3339),
+		Timezone:   loc.String(),
+		Days:       []CalendarDay{},
+		NextCursor: nextCursor,
+	}
+	for _, s := range schedules {
+		s = s.In(loc)
+		date := s.StartTime.Format(dateLayout)
+		if n := len(resp.Days); n == 0 || resp.Days[n-1].Date != date {
+			resp.Days = append(resp.Days, CalendarDay{Date: date})
+		}
+		day := &resp.Days[len(resp.Days)-1]
+		day.Items = append(day.Items, ToScheduleResponseDTO(s))
+	}
+	return resp
+}
+
+// ParseRange reads the bounds of /schedule/range. Each bound is an RFC 3339
+// time or a YYYY-MM-DD date in loc; a date as "to" includes that whole day.
+func ParseRange(fromValue, toValue string, loc *time.Location) (time.Time, time.Time, error) {
+	if fromValue == "" || toValue == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("from and to are required")
+	}
+	from, _, err := parseBound("from", fromValue, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, isDate, err := parseBound("to", toValue, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if isDate {
+		to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return checkRange(from, to)
+}
+
+// ParseDay returns the bounds of a YYYY-MM-DD day in loc
+func ParseDay(value string, loc *time.Location) (time.Time, time.Time, error) {
+	d, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("date %q must be in YYYY-MM-DD format", value)
+	}
+	// time.Date normalizes the day after, so DST days are 23 or 25 hours long
+	return d, time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc), nil
+}
+
+// ParseISOWeek returns the bounds of an ISO 8601 week such as "2025-W32",
+// Monday to Monday in loc
+func ParseISOWeek(value string, loc *time.Location) (time.Time, time.Time, error) {
+	m := isoWeekPattern.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("week %q must be in YYYY-Www format", value)
+	}
+	year, _ := strconv.Atoi(m[1])
+	week, _ := strconv.Atoi(m[2])
+
+	// January 4th is always in week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	monday := time.Date(year, time.January, 4-offset+(week-1)*7, 0, 0, 0, 0, loc)
+	if y, w := monday.ISOWeek(); week < 1 || y != year || w != week {
+		return time.Time{}, time.Time{}, fmt.Errorf("week %q does not exist", value)
+	}
+	return monday, time.Date(monday.Year(), monday.Month(), monday.Day()+7, 0, 0, 0, 0, loc), nil
+}
+
+// ParseMonth returns the bounds of a YYYY-MM month in loc
+func ParseMonth(value string, loc *time.Location) (time.Time, time.Time, error) {
+	m, err := time.ParseInLocation("2006-01", value, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("month %q must be in YYYY-MM format", value)
+	}
+	return m, time.Date(m.Year(), m.Month()+1, 1, 0, 0, 0, 0, loc), nil
+}
+
+func parseBound(field, value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	if d, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return d, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", field)
+}
+
+func checkRange(from, to time.Time) (time.Time, time.Time, error) {
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from) > MaxCalendarRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("range cannot be longer than 366 days")
+	}
+	return from, to, nil
+}
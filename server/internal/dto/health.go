@@ -0,0 +1,60 @@
+package dto
+
+import "database/sql"
+
+// CheckResult is the outcome of one readiness check: "ok", "degraded" or
+// "fail". Why a check failed is only logged, since /readyz is served
+// without authentication.
+type CheckResult struct {
+	Status string `json:"status"`
+}
+
+// DBPoolStats describes the database connection pool
+type DBPoolStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// ReadinessResponse lists every readiness check by name
+type ReadinessResponse struct {
+	Checks map[string]CheckResult `json:"checks"`
+	DBPool DBPoolStats            `json:"db_pool"`
+}
+
+// CronJobDTO is a background job and its cron spec
+type CronJobDTO struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+}
+
+// VersionResponse describes the running build
+type VersionResponse struct {
+	Commit      string       `json:"commit"`
+	BuildTime   string       `json:"build_time"`
+	GoVersion   string       `json:"go_version"`
+	AIProvider  string       `json:"ai_provider"`
+	AIProviders []string     `json:"ai_providers"`
+	DBDriver    string       `json:"db_driver"`
+	Cron        []CronJobDTO `json:"cron"`
+}
+
+func ToDBPoolStats(s sql.DBStats) DBPoolStats {
+	return DBPoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.String(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
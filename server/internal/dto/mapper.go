@@ -1,7 +1,10 @@
 package dto
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"murim-helper/internal/domain"
 	"strings"
 	"time"
@@ -18,6 +21,12 @@ func ToScheduleResponseDTO(s domain.Schedule) ScheduleResponseDTO {
 		repeatUntil = &str
 	}
 
+	var occurrenceStart *string
+	if s.OccurrenceStart != nil {
+		str := s.OccurrenceStart.Format(time.RFC3339)
+		occurrenceStart = &str
+	}
+
 	return ScheduleResponseDTO{
 		ID:          s.ID,
 		Title:       s.Title,
@@ -28,6 +37,11 @@ func ToScheduleResponseDTO(s domain.Schedule) ScheduleResponseDTO {
 		CreatedAt:   s.CreatedAt.Format(time.RFC3339),
 		RepeatType:  s.RepeatType,
 		RepeatUntil: repeatUntil,
+		RRule:       s.RRule,
+
+		SeriesID:        s.SeriesID,
+		Timezone:        s.Timezone,
+		OccurrenceStart: occurrenceStart,
 	}
 }
 
@@ -39,6 +53,86 @@ func ToScheduleResponseDTOs(schedules []domain.Schedule) []ScheduleResponseDTO {
 	return result
 }
 
+func ToSchedulePreviewResponse(p domain.SchedulePreview) SchedulePreviewResponse {
+	return SchedulePreviewResponse{
+		Token:     p.Token,
+		ExpiresAt: p.ExpiresAt.Format(time.RFC3339),
+		Items:     ToScheduleResponseDTOs(p.Schedules),
+		Conflicts: ToConflictDTOs(p.Conflicts),
+	}
+}
+
+func ToGenerationMeta(result domain.GenerationResult) GenerationMeta {
+	return GenerationMeta{
+		Provider:       result.Provider,
+		FailedAttempts: result.Attempts,
+		Conflicts:      ToConflictDTOs(result.Conflicts),
+	}
+}
+
+func ToConflictDTOs(conflicts []domain.Conflict) []ConflictDTO {
+	result := make([]ConflictDTO, len(conflicts))
+	for i, c := range conflicts {
+		result[i] = ConflictDTO{
+			ScheduleID:       c.ScheduleID,
+			Title:            c.Title,
+			ConflictingID:    c.ConflictingID,
+			ConflictingTitle: c.ConflictingTitle,
+			Start:            c.Start.Format(time.RFC3339),
+			End:              c.End.Format(time.RFC3339),
+		}
+	}
+	return result
+}
+
+func ToTimeSlotDTOs(slots []domain.TimeSlot, loc *time.Location) []TimeSlotDTO {
+	result := make([]TimeSlotDTO, len(slots))
+	for i, slot := range slots {
+		result[i] = TimeSlotDTO{
+			Start:           slot.Start.In(loc).Format(time.RFC3339),
+			End:             slot.End.In(loc).Format(time.RFC3339),
+			DurationMinutes: int(slot.Duration() / time.Minute),
+		}
+	}
+	return result
+}
+
+func ToReplanResponse(p domain.ReplanProposal, loc *time.Location) ReplanResponse {
+	moves := make([]ReplanMoveDTO, len(p.Diff.Moves))
+	for i, m := range p.Diff.Moves {
+		moves[i] = ReplanMoveDTO{
+			ScheduleID: m.ScheduleID,
+			Title:      m.Title,
+			FromStart:  m.FromStart.In(loc).Format(time.RFC3339),
+			FromEnd:    m.FromEnd.In(loc).Format(time.RFC3339),
+			StartTime:  m.StartTime.In(loc).Format(time.RFC3339),
+			EndTime:    m.EndTime.In(loc).Format(time.RFC3339),
+		}
+	}
+	deletions := make([]ReplanDeletionDTO, len(p.Diff.Deletions))
+	for i, d := range p.Diff.Deletions {
+		deletions[i] = ReplanDeletionDTO{
+			ScheduleID: d.ScheduleID,
+			Title:      d.Title,
+			StartTime:  d.StartTime.In(loc).Format(time.RFC3339),
+			Reason:     d.Reason,
+		}
+	}
+	additions := make([]ScheduleResponseDTO, len(p.Diff.Additions))
+	for i, a := range p.Diff.Additions {
+		additions[i] = ToScheduleResponseDTO(a.In(loc))
+	}
+	return ReplanResponse{
+		Token:       p.Token,
+		Date:        p.Day.Format("2006-01-02"),
+		Instruction: p.Instruction,
+		ExpiresAt:   p.ExpiresAt.In(loc).Format(time.RFC3339),
+		Moves:       moves,
+		Deletions:   deletions,
+		Additions:   additions,
+	}
+}
+
 // ======================
 // Request DTOs
 // ======================
@@ -54,6 +148,19 @@ func (r GenerateScheduleRequest) Validate() error {
 	return nil
 }
 
+// maxInstructionLength bounds the replan instruction sent to the AI
+const maxInstructionLength = 1000
+
+func (r ReplanRequest) Validate() error {
+	if strings.TrimSpace(r.Instruction) == "" {
+		return errors.New("instruction is required")
+	}
+	if len(r.Instruction) > maxInstructionLength {
+		return fmt.Errorf("instruction must be at most %d characters", maxInstructionLength)
+	}
+	return nil
+}
+
 func (r *CreateScheduleRequest) Validate() error {
 	if strings.TrimSpace(r.Title) == "" {
 		return errors.New("title is required")
@@ -64,9 +171,18 @@ func (r *CreateScheduleRequest) Validate() error {
 	if r.StartTime.After(r.EndTime) {
 		return errors.New("start_time must be before end_time")
 	}
-	if r.RepeatType == "" {
-		r.RepeatType = "none"
+	if r.Timezone != "" {
+		if _, err := domain.LoadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("%w %q", domain.ErrInvalidTimezone, r.Timezone)
+		}
+	}
+
+	schedule := r.ToDomain()
+	if err := schedule.NormalizeRecurrence(); err != nil {
+		return err
 	}
+	r.RepeatType = schedule.RepeatType
+	r.RRule = schedule.RRule
 	return nil
 }
 
@@ -79,13 +195,139 @@ func (r CreateScheduleRequest) ToDomain() domain.Schedule {
 		IsDone:      false,
 		RepeatType:  r.RepeatType,
 		RepeatUntil: r.RepeatUntil,
+		RRule:       r.RRule,
+		Timezone:    r.Timezone,
+	}
+}
+
+func (r *CommitPreviewRequest) Validate() error {
+	for i := range r.Items {
+		if err := r.Items[i].Validate(); err != nil {
+			return fmt.Errorf("items[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ToDomain returns nil when no edits were sent
+func (r CommitPreviewRequest) ToDomain() []domain.Schedule {
+	if r.Items == nil {
+		return nil
 	}
+	schedules := make([]domain.Schedule, len(r.Items))
+	for i, item := range r.Items {
+		schedules[i] = item.ToDomain()
+	}
+	return schedules
+}
+
+// maxBulkCreate bounds how many schedules one request may create
+const maxBulkCreate = 100
+
+func (r *CreateSchedulesRequest) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		r.Bulk = true
+		return json.Unmarshal(trimmed, &r.Items)
+	}
+	var item CreateScheduleRequest
+	if err := json.Unmarshal(trimmed, &item); err != nil {
+		return err
+	}
+	r.Items = []CreateScheduleRequest{item}
+	return nil
+}
+
+func (r *CreateSchedulesRequest) Validate() error {
+	if len(r.Items) == 0 {
+		return errors.New("at least one schedule is required")
+	}
+	if len(r.Items) > maxBulkCreate {
+		return fmt.Errorf("at most %d schedules can be created at once", maxBulkCreate)
+	}
+	for i := range r.Items {
+		if err := r.Items[i].Validate(); err != nil {
+			if !r.Bulk {
+				return err
+			}
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r CreateSchedulesRequest) ToDomain() []domain.Schedule {
+	schedules := make([]domain.Schedule, len(r.Items))
+	for i, item := range r.Items {
+		schedules[i] = item.ToDomain()
+	}
+	return schedules
+}
+
+// maxQuickAddLength bounds the quick-add text
+const maxQuickAddLength = 500
+
+func (r QuickAddRequest) Validate() error {
+	if strings.TrimSpace(r.Text) == "" {
+		return errors.New("text is required")
+	}
+	if len(r.Text) > maxQuickAddLength {
+		return fmt.Errorf("text must be at most %d characters", maxQuickAddLength)
+	}
+	return nil
+}
+
+func (r *AutoPlaceRequest) Validate() error {
+	if strings.TrimSpace(r.Title) == "" {
+		return errors.New("title is required")
+	}
+	if r.DurationMinutes <= 0 || r.DurationMinutes > 24*60 {
+		return errors.New("duration_minutes must be between 1 and 1440")
+	}
+	r.Priority = strings.ToLower(strings.TrimSpace(r.Priority))
+	switch r.Priority {
+	case "":
+		r.Priority = domain.PriorityNormal
+	case domain.PriorityLow, domain.PriorityNormal, domain.PriorityHigh:
+	default:
+		return fmt.Errorf("priority %q must be low, normal or high", r.Priority)
+	}
+	if r.Deadline != nil && r.Earliest != nil && !r.Earliest.Before(*r.Deadline) {
+		return errors.New("earliest must be before deadline")
+	}
+	return nil
+}
+
+func (r AutoPlaceRequest) ToDomain() domain.PlacementTask {
+	task := domain.PlacementTask{
+		Title:       r.Title,
+		Description: r.Description,
+		Duration:    time.Duration(r.DurationMinutes) * time.Minute,
+		Deadline:    r.Deadline,
+		Priority:    r.Priority,
+	}
+	if r.Earliest != nil {
+		task.Earliest = *r.Earliest
+	}
+	return task
 }
 
 func (r UpdateScheduleRequest) Validate() error {
 	if r.StartTime != nil && r.EndTime != nil && r.StartTime.After(*r.EndTime) {
 		return errors.New("start_time must be before end_time")
 	}
+	if r.RRule != nil && *r.RRule != "" {
+		s := domain.Schedule{RRule: *r.RRule}
+		if err := s.NormalizeRecurrence(); err != nil {
+			return err
+		}
+	}
+	if r.RRule == nil && r.RepeatType != nil {
+		s := domain.Schedule{RepeatType: *r.RepeatType}
+		if err := s.NormalizeRecurrence(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -105,11 +347,24 @@ func (r UpdateScheduleRequest) ToDomain(existing domain.Schedule) domain.Schedul
 	if r.IsDone != nil {
 		existing.IsDone = *r.IsDone
 	}
-	if r.RepeatType != nil {
+
+	switch {
+	case r.RRule != nil:
+		existing.RRule = *r.RRule
+		if *r.RRule == "" {
+			existing.RepeatType = domain.RepeatNone
+		}
+		if r.RepeatUntil != nil {
+			// Validate already checked the rule
+			_ = existing.SetRepeatUntil(r.RepeatUntil)
+		}
+	case r.RepeatType != nil:
+		// A legacy repeat type replaces the whole rule
+		existing.RRule = ""
 		existing.RepeatType = *r.RepeatType
-	}
-	if r.RepeatUntil != nil {
 		existing.RepeatUntil = r.RepeatUntil
+	case r.RepeatUntil != nil:
+		_ = existing.SetRepeatUntil(r.RepeatUntil)
 	}
 	return existing
 }
@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"murim-helper/internal/domain"
+	"time"
+)
+
+type UserProfileRequest struct {
+	Occupation   string             `json:"occupation"`
+	WakeTime     string             `json:"wake_time"`
+	SleepTime    string             `json:"sleep_time"`
+	Timezone     string             `json:"timezone"`
+	WorkStart    string             `json:"work_start"`
+	WorkEnd      string             `json:"work_end"`
+	Habits       []domain.Habit     `json:"habits"`
+	BlockedTimes []domain.TimeBlock `json:"blocked_times"`
+}
+
+type UserProfileResponse struct {
+	Occupation   string             `json:"occupation"`
+	WakeTime     string             `json:"wake_time"`
+	SleepTime    string             `json:"sleep_time"`
+	Timezone     string             `json:"timezone"`
+	WorkStart    string             `json:"work_start,omitempty"`
+	WorkEnd      string             `json:"work_end,omitempty"`
+	Habits       []domain.Habit     `json:"habits"`
+	BlockedTimes []domain.TimeBlock `json:"blocked_times"`
+	UpdatedAt    *string            `json:"updated_at,omitempty"`
+}
+
+func (r UserProfileRequest) ToDomain() domain.UserProfile {
+	return domain.UserProfile{
+		Occupation:   r.Occupation,
+		WakeTime:     r.WakeTime,
+		SleepTime:    r.SleepTime,
+		Timezone:     r.Timezone,
+		WorkStart:    r.WorkStart,
+		WorkEnd:      r.WorkEnd,
+		Habits:       r.Habits,
+		BlockedTimes: r.BlockedTimes,
+	}
+}
+
+func ToUserProfileResponse(p domain.UserProfile) UserProfileResponse {
+	var updatedAt *string
+	if !p.UpdatedAt.IsZero() {
+		str := p.UpdatedAt.Format(time.RFC3339)
+		updatedAt = &str
+	}
+
+	return UserProfileResponse{
+		Occupation:   p.Occupation,
+		WakeTime:     p.WakeTime,
+		SleepTime:    p.SleepTime,
+		Timezone:     p.Timezone,
+		WorkStart:    p.WorkStart,
+		WorkEnd:      p.WorkEnd,
+		Habits:       p.Habits,
+		BlockedTimes: p.BlockedTimes,
+		UpdatedAt:    updatedAt,
+	}
+}
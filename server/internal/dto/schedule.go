@@ -1,6 +1,9 @@
 package dto
 
-import "time"
+import (
+	"murim-helper/internal/domain"
+	"time"
+)
 
 type ScheduleCreateDTO struct {
 	Title       string  `json:"title"`
@@ -31,6 +34,11 @@ type ScheduleResponseDTO struct {
 	CreatedAt   string  `json:"created_at"`
 	RepeatType  string  `json:"repeat_type"`
 	RepeatUntil *string `json:"repeat_until,omitempty"`
+	RRule       string  `json:"rrule,omitempty"`
+	SeriesID    string  `json:"series_id,omitempty"`
+	Timezone    string  `json:"timezone,omitempty"`
+	// Set on occurrences of a recurring schedule; their ID is "<schedule id>_<original start>"
+	OccurrenceStart *string `json:"occurrence_start,omitempty"`
 }
 
 type CreateScheduleRequest struct {
@@ -40,6 +48,15 @@ type CreateScheduleRequest struct {
 	EndTime     time.Time  `json:"end_time"`
 	RepeatType  string     `json:"repeat_type"`
 	RepeatUntil *time.Time `json:"repeat_until,omitempty"`
+	RRule       string     `json:"rrule,omitempty"`    // RFC 5545, e.g. "FREQ=WEEKLY;BYDAY=MO,WE,FR"
+	Timezone    string     `json:"timezone,omitempty"` // IANA name, defaults to the profile timezone
+}
+
+// CreateSchedulesRequest is the body of a manual create: a single schedule
+// object, or a JSON array of them for a bulk create
+type CreateSchedulesRequest struct {
+	Items []CreateScheduleRequest
+	Bulk  bool
 }
 
 type UpdateScheduleRequest struct {
@@ -50,6 +67,94 @@ type UpdateScheduleRequest struct {
 	IsDone      *bool      `json:"is_done,omitempty"`
 	RepeatType  *string    `json:"repeat_type,omitempty"`
 	RepeatUntil *time.Time `json:"repeat_until,omitempty"`
+	RRule       *string    `json:"rrule,omitempty"` // empty string removes the recurrence
+}
+
+type SchedulePreviewResponse struct {
+	Token     string                `json:"token"`
+	ExpiresAt string                `json:"expires_at"`
+	Items     []ScheduleResponseDTO `json:"items"`
+	Conflicts []ConflictDTO         `json:"conflicts,omitempty"`
+}
+
+// QuickAddRequest is one line of plain text describing a single schedule
+type QuickAddRequest struct {
+	Text string `json:"text"` // e.g. "lunch with Budi tomorrow 12:30 for 1h every Friday"
+}
+
+// AutoPlaceRequest asks for a task to be placed in the user's free time
+type AutoPlaceRequest struct {
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Deadline        *time.Time `json:"deadline,omitempty"` // must be finished by, defaults to a week from now
+	Earliest        *time.Time `json:"earliest,omitempty"` // do not start before, defaults to now
+	Priority        string     `json:"priority,omitempty"` // low, normal (default) or high
+}
+
+// TimeSlotDTO is a free period in the calendar
+type TimeSlotDTO struct {
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// ConflictDTO is a pair of overlapping schedules. ScheduleID is the one
+// being created or changed when there is one.
+type ConflictDTO struct {
+	ScheduleID       string `json:"schedule_id"`
+	Title            string `json:"title"`
+	ConflictingID    string `json:"conflicting_id"`
+	ConflictingTitle string `json:"conflicting_title"`
+	Start            string `json:"start"` // start of the overlap
+	End              string `json:"end"`   // end of the overlap
+}
+
+// ConflictMeta warns about overlaps a change was saved with, or lists the
+// ones it was rejected for
+type ConflictMeta struct {
+	Conflicts []ConflictDTO `json:"conflicts"`
+}
+
+// ReplanRequest is the user's instruction for changing a planned day
+type ReplanRequest struct {
+	Instruction string `json:"instruction"` // e.g. "I woke up late, push everything back an hour"
+}
+
+// ReplanMoveDTO is an existing schedule or occurrence moved to a new time
+type ReplanMoveDTO struct {
+	ScheduleID string `json:"schedule_id"`
+	Title      string `json:"title"`
+	FromStart  string `json:"from_start"`
+	FromEnd    string `json:"from_end"`
+	StartTime  string `json:"start_time"`
+	EndTime    string `json:"end_time"`
+}
+
+// ReplanDeletionDTO is an existing schedule or occurrence dropped from the day
+type ReplanDeletionDTO struct {
+	ScheduleID string `json:"schedule_id"`
+	Title      string `json:"title"`
+	StartTime  string `json:"start_time"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// ReplanResponse is a proposed diff for a day. Accept it with
+// POST /schedule/replan/{token}/accept before it expires.
+type ReplanResponse struct {
+	Token       string                `json:"token"`
+	Date        string                `json:"date"`
+	Instruction string                `json:"instruction"`
+	ExpiresAt   string                `json:"expires_at"`
+	Moves       []ReplanMoveDTO       `json:"moves"`
+	Deletions   []ReplanDeletionDTO   `json:"deletions"`
+	Additions   []ScheduleResponseDTO `json:"additions"`
+}
+
+// CommitPreviewRequest optionally replaces the previewed items with the
+// user's edited list; an empty body commits the preview as generated.
+type CommitPreviewRequest struct {
+	Items []CreateScheduleRequest `json:"items,omitempty"`
 }
 
 // PaginatedResponse is a generic wrapper for paginated API responses
@@ -60,3 +165,10 @@ type PaginatedResponse struct {
 	TotalItems int         `json:"total_items"` // Total number of items in DB
 	TotalPages int         `json:"total_pages"` // Total number of pages
 }
+
+// GenerationMeta tells the client which AI provider produced a schedule
+type GenerationMeta struct {
+	Provider       string                   `json:"provider"`
+	FailedAttempts []domain.ProviderAttempt `json:"failed_attempts,omitempty"`
+	Conflicts      []ConflictDTO            `json:"conflicts,omitempty"`
+}
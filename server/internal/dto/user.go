@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"errors"
+	"murim-helper/internal/domain"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type UserResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (r CreateUserRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	if _, err := mail.ParseAddress(strings.TrimSpace(r.Email)); err != nil {
+		return errors.New("a valid email is required")
+	}
+	return nil
+}
+
+func ToUserResponse(u domain.User) UserResponse {
+	return UserResponse{
+		ID:        u.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt.Format(time.RFC3339),
+	}
+}
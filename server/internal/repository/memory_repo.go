@@ -0,0 +1,823 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exceptionKey identifies a row of schedule_exceptions
+type exceptionKey struct {
+	scheduleID string
+	start      int64 // UnixNano of the occurrence start
+}
+
+func keyOf(scheduleID string, start time.Time) exceptionKey {
+	return exceptionKey{scheduleID: scheduleID, start: start.UnixNano()}
+}
+
+// MemoryRepo keeps everything in memory, for tests and demos. It is safe for
+// concurrent use and behaves like PostgresRepo, except that it does not
+// check foreign keys and loses its data when the process exits.
+type MemoryRepo struct {
+	mu         sync.RWMutex
+	schedules  map[string]domain.Schedule
+	exceptions map[exceptionKey]domain.ScheduleException
+	previews   map[string]domain.SchedulePreview
+	replans    map[string]memoryReplan
+	profiles   map[string]domain.UserProfile
+	users      map[string]domain.User
+	apiKeys    map[string]domain.APIKey
+
+	jobs jobLocks
+}
+
+// memoryReplan is a replan as the database stores it: the day as a date
+// and the diff as JSON
+type memoryReplan struct {
+	proposal domain.ReplanProposal
+	day      string
+	diff     []byte
+}
+
+// NewMemoryRepo returns an empty repository holding only the default user
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		schedules:  map[string]domain.Schedule{},
+		exceptions: map[exceptionKey]domain.ScheduleException{},
+		previews:   map[string]domain.SchedulePreview{},
+		replans:    map[string]memoryReplan{},
+		profiles:   map[string]domain.UserProfile{},
+		users: map[string]domain.User{
+			domain.DefaultUserID: {ID: domain.DefaultUserID, Name: "Default user", Email: "default@murimhelper.local", CreatedAt: time.Now()},
+		},
+		apiKeys: map[string]domain.APIKey{},
+	}
+}
+
+// copyTime returns a copy of t so stored rows never share memory with callers
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func copySchedule(s domain.Schedule) domain.Schedule {
+	s.RepeatUntil = copyTime(s.RepeatUntil)
+	s.OccurrenceStart = copyTime(s.OccurrenceStart)
+	return s
+}
+
+func copyException(e domain.ScheduleException) domain.ScheduleException {
+	e.Title = copyString(e.Title)
+	e.Description = copyString(e.Description)
+	e.StartTime = copyTime(e.StartTime)
+	e.EndTime = copyTime(e.EndTime)
+	return e
+}
+
+func copyAPIKey(k domain.APIKey) domain.APIKey {
+	k.LastUsedAt = copyTime(k.LastUsedAt)
+	k.RevokedAt = copyTime(k.RevokedAt)
+	return k
+}
+
+// jsonCopy round-trips src through JSON into dst, the way the database
+// stores previews, replans and profile lists
+func jsonCopy(src, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+// SaveMany inserts multiple schedules, all or none
+func (r *MemoryRepo) SaveMany(ctx context.Context, schedules []domain.Schedule) error {
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkNewSchedules(schedules); err != nil {
+		return err
+	}
+	r.insertSchedules(schedules)
+	return nil
+}
+
+// checkNewSchedules fails like the primary key would on a duplicate ID
+func (r *MemoryRepo) checkNewSchedules(schedules []domain.Schedule) error {
+	seen := map[string]bool{}
+	for _, s := range schedules {
+		if _, ok := r.schedules[s.ID]; ok || seen[s.ID] {
+			return fmt.Errorf("batch insert failed: duplicate schedule id %q", s.ID)
+		}
+		seen[s.ID] = true
+	}
+	return nil
+}
+
+func (r *MemoryRepo) insertSchedules(schedules []domain.Schedule) {
+	now := time.Now()
+	for _, s := range schedules {
+		s = copySchedule(s)
+		if s.SeriesID == "" {
+			s.SeriesID = s.ID
+		}
+		if s.Timezone == "" {
+			s.Timezone = "UTC"
+		}
+		s.CreatedAt = now
+		s.OccurrenceStart = nil
+		r.schedules[s.ID] = s
+	}
+}
+
+func (r *MemoryRepo) Update(ctx context.Context, userID, id string, updated domain.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.updateSchedule(userID, id, updated)
+}
+
+func (r *MemoryRepo) updateSchedule(userID, id string, updated domain.Schedule) error {
+	s, ok := r.schedules[id]
+	if !ok || s.UserID != userID {
+		return sql.ErrNoRows
+	}
+	s.Title = updated.Title
+	s.Description = updated.Description
+	s.StartTime = updated.StartTime
+	s.EndTime = updated.EndTime
+	s.IsDone = updated.IsDone
+	s.RepeatType = updated.RepeatType
+	s.RepeatUntil = copyTime(updated.RepeatUntil)
+	s.RRule = updated.RRule
+	r.schedules[id] = s
+	return nil
+}
+
+func (r *MemoryRepo) GetAll(ctx context.Context, userID string, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := r.filterSchedules(userID, filter, func(domain.Schedule) bool { return true })
+	sortSchedules(matches, filter.SortBy, strings.ToLower(filter.SortOrder) == "desc")
+
+	// Pagination
+	total := len(matches)
+	offset := (page - 1) * limit
+	if offset < 0 || limit < 0 {
+		return nil, 0, fmt.Errorf("failed to fetch schedules: negative limit or offset")
+	}
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	end := offset + limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	return matches[offset:end], total, nil
+}
+
+// filterSchedules returns copies of the schedules of the user that match
+// filter and keep, in no particular order
+func (r *MemoryRepo) filterSchedules(userID string, filter dto.ScheduleFilter, keep func(domain.Schedule) bool) []domain.Schedule {
+	var search *regexp.Regexp
+	if filter.Search != "" {
+		search = ilikePattern("%" + filter.Search + "%")
+	}
+
+	var matches []domain.Schedule
+	for _, s := range r.schedules {
+		if s.UserID != userID || !keep(s) {
+			continue
+		}
+		if filter.IsDone != nil && s.IsDone != *filter.IsDone {
+			continue
+		}
+		if filter.RepeatType != "" && s.RepeatType != filter.RepeatType {
+			continue
+		}
+		if search != nil && !search.MatchString(s.Title) && !search.MatchString(s.Description) {
+			continue
+		}
+		if filter.StartAfter != nil && s.StartTime.Before(*filter.StartAfter) {
+			continue
+		}
+		if filter.StartBefore != nil && !s.StartTime.Before(*filter.StartBefore) {
+			continue
+		}
+		matches = append(matches, copySchedule(s))
+	}
+	return matches
+}
+
+// ilikePattern compiles an ILIKE pattern, where % matches any run of
+// characters and _ a single one
+func ilikePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for _, c := range pattern {
+		switch c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// sortSchedules orders schedules by one of the columns GetAll allows,
+// start_time by default. Ties are broken by ID so pages are stable.
+func sortSchedules(schedules []domain.Schedule, sortBy string, desc bool) {
+	less := func(a, b domain.Schedule) int {
+		switch sortBy {
+		case "end_time":
+			return a.EndTime.Compare(b.EndTime)
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case "title":
+			return strings.Compare(a.Title, b.Title)
+		default:
+			return a.StartTime.Compare(b.StartTime)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		c := less(schedules[i], schedules[j])
+		if desc {
+			c = -c
+		}
+		if c == 0 {
+			return schedules[i].ID < schedules[j].ID
+		}
+		return c < 0
+	})
+}
+
+// GetOneOffs returns every non-recurring schedule matching filter, unpaginated
+func (r *MemoryRepo) GetOneOffs(ctx context.Context, userID string, filter dto.ScheduleFilter) ([]domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := r.filterSchedules(userID, filter, func(s domain.Schedule) bool { return s.RRule == "" })
+	sortSchedules(schedules, "start_time", false)
+	return schedules, nil
+}
+
+// GetSeriesInRange returns the recurring series of the user that can have
+// occurrences starting in [from, to)
+func (r *MemoryRepo) GetSeriesInRange(ctx context.Context, userID string, from, to time.Time) ([]domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.seriesInRange(func(s domain.Schedule) bool { return s.UserID == userID }, from, to), nil
+}
+
+func (r *MemoryRepo) seriesInRange(keep func(domain.Schedule) bool, from, to time.Time) []domain.Schedule {
+	var schedules []domain.Schedule
+	for _, s := range r.schedules {
+		if !keep(s) || s.RRule == "" || !s.StartTime.Before(to) {
+			continue
+		}
+		if s.RepeatUntil != nil && s.RepeatUntil.Before(from) {
+			continue
+		}
+		schedules = append(schedules, copySchedule(s))
+	}
+	sortSchedules(schedules, "start_time", false)
+	return schedules
+}
+
+// GetExceptions returns the exceptions of the given series whose original or
+// overridden start falls in [from, to)
+func (r *MemoryRepo) GetExceptions(ctx context.Context, scheduleIDs []string, from, to time.Time) ([]domain.ScheduleException, error) {
+	var exceptions []domain.ScheduleException
+	if len(scheduleIDs) == 0 {
+		return exceptions, nil
+	}
+
+	ids := map[string]bool{}
+	for _, id := range scheduleIDs {
+		ids[id] = true
+	}
+	inRange := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, exc := range r.exceptions {
+		if !ids[exc.ScheduleID] {
+			continue
+		}
+		if inRange(exc.OccurrenceStart) || (exc.StartTime != nil && inRange(*exc.StartTime)) {
+			exceptions = append(exceptions, copyException(exc))
+		}
+	}
+	return exceptions, nil
+}
+
+// GetException returns the exception of one occurrence, or sql.ErrNoRows
+func (r *MemoryRepo) GetException(ctx context.Context, scheduleID string, occurrenceStart time.Time) (*domain.ScheduleException, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exc, ok := r.exceptions[keyOf(scheduleID, occurrenceStart)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	exc = copyException(exc)
+	return &exc, nil
+}
+
+func (r *MemoryRepo) GetByID(ctx context.Context, userID, id string) (*domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.schedules[id]
+	if !ok || s.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	s = copySchedule(s)
+	return &s, nil
+}
+
+func (r *MemoryRepo) DeleteByID(ctx context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.schedules[id]
+	if !ok || s.UserID != userID {
+		return sql.ErrNoRows
+	}
+	r.deleteSchedule(id)
+	return nil
+}
+
+// deleteSchedule removes a schedule with its exceptions,
+// like the ON DELETE CASCADE foreign keys do
+func (r *MemoryRepo) deleteSchedule(id string) {
+	delete(r.schedules, id)
+	for key := range r.exceptions {
+		if key.scheduleID == id {
+			delete(r.exceptions, key)
+		}
+	}
+}
+
+// DeleteAll removes every schedule owned by the user
+func (r *MemoryRepo) DeleteAll(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, s := range r.schedules {
+		if s.UserID == userID {
+			r.deleteSchedule(id)
+		}
+	}
+	return nil
+}
+
+// SaveException creates or replaces the exception of one occurrence
+func (r *MemoryRepo) SaveException(ctx context.Context, exc domain.ScheduleException) error {
+	return r.SaveExceptions(ctx, []domain.ScheduleException{exc})
+}
+
+// SaveExceptions creates or replaces several exceptions
+func (r *MemoryRepo) SaveExceptions(ctx context.Context, exceptions []domain.ScheduleException) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saveExceptions(exceptions)
+	return nil
+}
+
+func (r *MemoryRepo) saveExceptions(exceptions []domain.ScheduleException) {
+	now := time.Now()
+	for _, exc := range exceptions {
+		exc = copyException(exc)
+		exc.UpdatedAt = now
+		r.exceptions[keyOf(exc.ScheduleID, exc.OccurrenceStart)] = exc
+	}
+}
+
+// GetSeriesRows returns every row of a series, oldest first, or
+// sql.ErrNoRows when the series does not exist
+func (r *MemoryRepo) GetSeriesRows(ctx context.Context, userID, seriesID string) ([]domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var schedules []domain.Schedule
+	for _, s := range r.schedules {
+		if s.UserID == userID && s.SeriesID == seriesID {
+			schedules = append(schedules, copySchedule(s))
+		}
+	}
+	if len(schedules) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	sortSchedules(schedules, "start_time", false)
+	return schedules, nil
+}
+
+// ApplySeriesChange saves an edit spanning several rows of a series, all or
+// none. Exceptions are moved before rows are deleted so that moving them off
+// a deleted row keeps them.
+func (r *MemoryRepo) ApplySeriesChange(ctx context.Context, userID string, change domain.SeriesChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.applySeriesChange(userID, change)
+}
+
+// applySeriesChange checks the whole change before applying any of it. The
+// caller holds the write lock.
+func (r *MemoryRepo) applySeriesChange(userID string, change domain.SeriesChange) error {
+	if err := r.checkNewSchedules(change.Insert); err != nil {
+		return err
+	}
+	for _, s := range change.Update {
+		if current, ok := r.schedules[s.ID]; !ok || current.UserID != userID {
+			if !inserting(change.Insert, s.ID, userID) {
+				return sql.ErrNoRows
+			}
+		}
+	}
+
+	r.insertSchedules(change.Insert)
+	for _, s := range change.Update {
+		r.updateSchedule(userID, s.ID, s)
+	}
+	r.saveExceptions(change.Exceptions)
+	for _, m := range change.Move {
+		var moved []domain.ScheduleException
+		for key, exc := range r.exceptions {
+			if exc.ScheduleID == m.FromID && !exc.OccurrenceStart.Before(m.Since) {
+				delete(r.exceptions, key)
+				moved = append(moved, exc)
+			}
+		}
+		for _, exc := range moved {
+			exc.ScheduleID = m.ToID
+			exc.Shift(m.Shift)
+			r.exceptions[keyOf(exc.ScheduleID, exc.OccurrenceStart)] = exc
+		}
+	}
+	for _, id := range change.Delete {
+		if s, ok := r.schedules[id]; ok && s.UserID == userID {
+			r.deleteSchedule(id)
+		}
+	}
+	return nil
+}
+
+// inserting reports whether the user's schedule id is among schedules
+func inserting(schedules []domain.Schedule, id, userID string) bool {
+	for _, s := range schedules {
+		if s.ID == id && s.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepo) SavePreview(ctx context.Context, preview domain.SchedulePreview) error {
+	stored := domain.SchedulePreview{
+		Token:     preview.Token,
+		UserID:    preview.UserID,
+		Provider:  preview.Provider,
+		CreatedAt: time.Now(),
+		ExpiresAt: preview.ExpiresAt,
+	}
+	if err := jsonCopy(preview.Schedules, &stored.Schedules); err != nil {
+		return fmt.Errorf("encode preview items failed: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.previews[preview.Token]; ok {
+		return fmt.Errorf("save preview failed: duplicate token")
+	}
+	r.previews[preview.Token] = stored
+	return nil
+}
+
+// GetPreview returns a preview that has not expired yet, or sql.ErrNoRows
+func (r *MemoryRepo) GetPreview(ctx context.Context, userID, token string) (*domain.SchedulePreview, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.previews[token]
+	if !ok || stored.UserID != userID || !stored.ExpiresAt.After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+	preview := stored
+	preview.Schedules = nil
+	if err := jsonCopy(stored.Schedules, &preview.Schedules); err != nil {
+		return nil, fmt.Errorf("decode preview items failed: %w", err)
+	}
+	return &preview, nil
+}
+
+// CommitPreview consumes the preview and saves the schedules at once, so a
+// token can only ever be committed once.
+func (r *MemoryRepo) CommitPreview(ctx context.Context, userID, token string, schedules []domain.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.previews[token]
+	if !ok || stored.UserID != userID || !stored.ExpiresAt.After(time.Now()) {
+		return sql.ErrNoRows
+	}
+	if err := r.checkNewSchedules(schedules); err != nil {
+		return err
+	}
+	delete(r.previews, token)
+	r.insertSchedules(schedules)
+	return nil
+}
+
+func (r *MemoryRepo) DeleteExpiredPreviews(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+	for token, p := range r.previews {
+		if !p.ExpiresAt.After(now) {
+			delete(r.previews, token)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryRepo) SaveReplan(ctx context.Context, proposal domain.ReplanProposal) error {
+	diff, err := json.Marshal(proposal.Diff)
+	if err != nil {
+		return fmt.Errorf("encode replan diff failed: %w", err)
+	}
+
+	stored := memoryReplan{
+		proposal: domain.ReplanProposal{
+			Token:       proposal.Token,
+			UserID:      proposal.UserID,
+			Instruction: proposal.Instruction,
+			Provider:    proposal.Provider,
+			CreatedAt:   time.Now(),
+			ExpiresAt:   proposal.ExpiresAt,
+		},
+		day:  proposal.Day.Format("2006-01-02"),
+		diff: diff,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.replans[proposal.Token]; ok {
+		return fmt.Errorf("save replan failed: duplicate token")
+	}
+	r.replans[proposal.Token] = stored
+	return nil
+}
+
+// GetReplan returns a replan that has not expired yet, or sql.ErrNoRows.
+// Day is midnight of the replanned date in loc.
+func (r *MemoryRepo) GetReplan(ctx context.Context, userID, token string, loc *time.Location) (*domain.ReplanProposal, error) {
+	r.mu.RLock()
+	stored, ok := r.replans[token]
+	r.mu.RUnlock()
+	if !ok || stored.proposal.UserID != userID || !stored.proposal.ExpiresAt.After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", stored.day, loc)
+	if err != nil {
+		return nil, fmt.Errorf("decode replan day failed: %w", err)
+	}
+	proposal := stored.proposal
+	proposal.Day = day
+	if err := json.Unmarshal(stored.diff, &proposal.Diff); err != nil {
+		return nil, fmt.Errorf("decode replan diff failed: %w", err)
+	}
+	return &proposal, nil
+}
+
+// DeleteReplan consumes a replan that has not expired yet, returning
+// sql.ErrNoRows when there is none, so a replan is only ever applied once
+func (r *MemoryRepo) DeleteReplan(ctx context.Context, userID, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.replans[token]
+	if !ok || stored.proposal.UserID != userID || !stored.proposal.ExpiresAt.After(time.Now()) {
+		return sql.ErrNoRows
+	}
+	delete(r.replans, token)
+	return nil
+}
+
+// AcceptReplan consumes a replan and saves the change it resolved to at
+// once. A change that cannot be applied leaves the replan to be retried.
+func (r *MemoryRepo) AcceptReplan(ctx context.Context, userID, token string, change domain.SeriesChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.replans[token]
+	if !ok || stored.proposal.UserID != userID || !stored.proposal.ExpiresAt.After(time.Now()) {
+		return sql.ErrNoRows
+	}
+	if err := r.applySeriesChange(userID, change); err != nil {
+		return err
+	}
+	delete(r.replans, token)
+	return nil
+}
+
+func (r *MemoryRepo) DeleteExpiredReplans(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+	for token, p := range r.replans {
+		if !p.proposal.ExpiresAt.After(now) {
+			delete(r.replans, token)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// GetProfile returns the stored profile, or sql.ErrNoRows if none was saved
+func (r *MemoryRepo) GetProfile(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	r.mu.RLock()
+	stored, ok := r.profiles[userID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	profile := stored
+	profile.Habits, profile.BlockedTimes = nil, nil
+	if err := jsonCopy(stored.Habits, &profile.Habits); err != nil {
+		return nil, fmt.Errorf("decode habits failed: %w", err)
+	}
+	if err := jsonCopy(stored.BlockedTimes, &profile.BlockedTimes); err != nil {
+		return nil, fmt.Errorf("decode blocked times failed: %w", err)
+	}
+	return &profile, nil
+}
+
+// SaveProfile creates or replaces the profile
+func (r *MemoryRepo) SaveProfile(ctx context.Context, profile domain.UserProfile) error {
+	stored := profile
+	stored.Habits, stored.BlockedTimes = nil, nil
+	if err := jsonCopy(profile.Habits, &stored.Habits); err != nil {
+		return fmt.Errorf("encode habits failed: %w", err)
+	}
+	if err := jsonCopy(profile.BlockedTimes, &stored.BlockedTimes); err != nil {
+		return fmt.Errorf("encode blocked times failed: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.profiles[profile.UserID] = stored
+	return nil
+}
+
+func (r *MemoryRepo) CreateUser(ctx context.Context, user domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return fmt.Errorf("create user failed: duplicate id %q", user.ID)
+	}
+	for _, u := range r.users {
+		if u.Email == user.Email {
+			return domain.ErrEmailTaken
+		}
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *MemoryRepo) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (r *MemoryRepo) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range r.apiKeys {
+		if k.ID == key.ID || k.KeyHash == key.KeyHash {
+			return fmt.Errorf("create api key failed: duplicate id or hash")
+		}
+	}
+	key = copyAPIKey(key)
+	key.LastUsedAt, key.RevokedAt = nil, nil
+	r.apiKeys[key.ID] = key
+	return nil
+}
+
+func (r *MemoryRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.apiKeys {
+		if k.KeyHash == hash {
+			k = copyAPIKey(k)
+			return &k, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// GetAPIKeyByID is not scoped to a user so callers can tell "not found"
+// apart from "owned by someone else"
+func (r *MemoryRepo) GetAPIKeyByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k, ok := r.apiKeys[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	k = copyAPIKey(k)
+	return &k, nil
+}
+
+func (r *MemoryRepo) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []domain.APIKey
+	for _, k := range r.apiKeys {
+		if k.UserID == userID {
+			keys = append(keys, copyAPIKey(k))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (r *MemoryRepo) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.apiKeys[id]
+	if !ok || k.UserID != userID || k.RevokedAt != nil {
+		return sql.ErrNoRows
+	}
+	k.RevokedAt = &at
+	r.apiKeys[id] = k
+	return nil
+}
+
+func (r *MemoryRepo) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k, ok := r.apiKeys[id]; ok {
+		k.LastUsedAt = &at
+		r.apiKeys[id] = k
+	}
+	return nil
+}
+
+// WithJobLock runs fn unless the job is already running
+func (r *MemoryRepo) WithJobLock(ctx context.Context, job string, fn func(context.Context) error) (bool, error) {
+	return r.jobs.run(ctx, job, fn)
+}
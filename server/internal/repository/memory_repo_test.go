@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"murim-helper/internal/repository"
+	"murim-helper/internal/repository/repotest"
+)
+
+func TestMemoryRepo(t *testing.T) {
+	repotest.TestRepository(t, func(t *testing.T) repository.Repository {
+		return repository.NewMemoryRepo()
+	})
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"murim-helper/db/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/lib/pq"
+)
+
+// Migrator returns a migrator for the embedded migrations. It holds one
+// connection of the pool until it is closed.
+func (r *PostgresRepo) Migrator(ctx context.Context) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection for migrations: %w", err)
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to prepare migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("failed to prepare migrations: %w", err)
+	}
+	return m, nil
+}
+
+// LatestMigration returns the version of the newest embedded migration
+func LatestMigration() (uint, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// CheckMigrations returns an error unless every embedded migration has been
+// applied and none failed halfway. It reads the version table directly, as
+// Migrator would hold a connection and take the migration lock.
+func (r *PostgresRepo) CheckMigrations(ctx context.Context) error {
+	latest, err := LatestMigration()
+	if err != nil {
+		return err
+	}
+
+	var version int64
+	var dirty bool
+	err = r.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Code == "42P01") {
+		return fmt.Errorf("no migrations applied, latest is %d", latest)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("schema is dirty at version %d", version)
+	case version < int64(latest):
+		return fmt.Errorf("schema is at version %d, latest is %d", version, latest)
+	case version > int64(latest):
+		return fmt.Errorf("schema is at version %d, newer than this build (%d)", version, latest)
+	}
+	return nil
+}
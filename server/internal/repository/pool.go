@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PoolConfig sizes the connection pool. Zero values keep the database/sql
+// defaults.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (c PoolConfig) apply(db *sqlx.DB) {
+	if c.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+}
+
+// Pinger is a backend that can check its database is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+const (
+	firstPingBackoff = 500 * time.Millisecond
+	maxPingBackoff   = 10 * time.Second
+)
+
+// PingWithRetry pings until the database answers, doubling the wait after
+// each failure up to maxPingBackoff, and gives up with the last error when
+// ctx is done
+func PingWithRetry(ctx context.Context, p Pinger) error {
+	backoff := firstPingBackoff
+	for attempt := 1; ; attempt++ {
+		err := p.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[DB] ping attempt %d failed: %v, retrying in %s", attempt, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %d attempt(s): %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxPingBackoff {
+			backoff = maxPingBackoff
+		}
+	}
+}
+
+func (r *PostgresRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics
+func (r *PostgresRepo) Stats() sql.DBStats {
+	return r.db.Stats()
+}
+
+func (r *SQLiteRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics
+func (r *SQLiteRepo) Stats() sql.DBStats {
+	return r.db.Stats()
+}
+
+// Ping always succeeds, there is no database to reach
+func (r *MemoryRepo) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Stats is empty, there is no connection pool
+func (r *MemoryRepo) Stats() sql.DBStats {
+	return sql.DBStats{}
+}
@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"murim-helper/internal/domain"
 	"murim-helper/internal/dto"
@@ -10,18 +13,21 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresRepo struct {
 	db *sqlx.DB
 }
 
-func NewPostgresRepo(connStr string) (*PostgresRepo, error) {
+// NewPostgresRepo prepares a pool of connections to connStr. It does not
+// connect; use PingWithRetry to wait for the database.
+func NewPostgresRepo(connStr string, pool PoolConfig) (*PostgresRepo, error) {
 	db, err := sqlx.Open("postgres", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open DB: %w", err)
 	}
+	pool.apply(db)
 	return &PostgresRepo{db: db}, nil
 }
 
@@ -36,42 +42,64 @@ func (r *PostgresRepo) SaveMany(ctx context.Context, schedules []domain.Schedule
 		return fmt.Errorf("begin transaction failed: %w", err)
 	}
 
+	if err := insertSchedules(ctx, tx, schedules); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// insertSchedules batch inserts schedules using the given transaction
+func insertSchedules(ctx context.Context, tx *sqlx.Tx, schedules []domain.Schedule) error {
 	query := `INSERT INTO schedules 
-		(id, title, description, start_time, end_time, is_done, repeat_type, repeat_until) VALUES `
+		(id, user_id, title, description, start_time, end_time, is_done, repeat_type, repeat_until, rrule, series_id, timezone) VALUES `
 
 	args := []interface{}{}
 	placeholders := []string{}
 
 	for i, s := range schedules {
-		idx := i * 8
+		seriesID := s.SeriesID
+		if seriesID == "" {
+			seriesID = s.ID
+		}
+		timezone := s.Timezone
+		if timezone == "" {
+			timezone = "UTC"
+		}
+
+		idx := i * 12
 		placeholders = append(placeholders,
-			fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
-				idx+1, idx+2, idx+3, idx+4, idx+5, idx+6, idx+7, idx+8))
+			fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				idx+1, idx+2, idx+3, idx+4, idx+5, idx+6, idx+7, idx+8, idx+9, idx+10, idx+11, idx+12))
 		args = append(args,
-			s.ID, s.Title, s.Description, s.StartTime, s.EndTime, s.IsDone, s.RepeatType, s.RepeatUntil)
+			s.ID, s.UserID, s.Title, s.Description, s.StartTime, s.EndTime, s.IsDone, s.RepeatType, s.RepeatUntil, s.RRule, seriesID, timezone)
 	}
 
 	query += strings.Join(placeholders, ",")
 	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
-		tx.Rollback()
 		return fmt.Errorf("batch insert failed: %w", err)
 	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit failed: %w", err)
-	}
 	return nil
 }
 
-func (r *PostgresRepo) Update(ctx context.Context, id string, updated domain.Schedule) error {
+func (r *PostgresRepo) Update(ctx context.Context, userID, id string, updated domain.Schedule) error {
+	return updateSchedule(ctx, r.db, userID, id, updated)
+}
+
+func updateSchedule(ctx context.Context, db sqlx.ExecerContext, userID, id string, updated domain.Schedule) error {
 	query := `
 		UPDATE schedules
-		SET title = $1, description = $2, start_time = $3, end_time = $4, is_done = $5, repeat_type = $6, repeat_until = $7
-		WHERE id = $8`
+		SET title = $1, description = $2, start_time = $3, end_time = $4, is_done = $5,
+			repeat_type = $6, repeat_until = $7, rrule = $8
+		WHERE id = $9 AND user_id = $10`
 
-	res, err := r.db.ExecContext(ctx, query,
+	res, err := db.ExecContext(ctx, query,
 		updated.Title, updated.Description, updated.StartTime, updated.EndTime,
-		updated.IsDone, updated.RepeatType, updated.RepeatUntil, id)
+		updated.IsDone, updated.RepeatType, updated.RepeatUntil, updated.RRule, id, userID)
 	if err != nil {
 		return fmt.Errorf("update failed: %w", err)
 	}
@@ -83,37 +111,11 @@ func (r *PostgresRepo) Update(ctx context.Context, id string, updated domain.Sch
 	return nil
 }
 
-func (r *PostgresRepo) GetAll(ctx context.Context, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error) {
+func (r *PostgresRepo) GetAll(ctx context.Context, userID string, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error) {
 	var schedules []domain.Schedule
-	var args []interface{}
-	var conditions []string
+	conditions, args := scheduleConditions(userID, filter)
 
-	if filter.IsDone != nil {
-		args = append(args, *filter.IsDone)
-		conditions = append(conditions, fmt.Sprintf("is_done = $%d", len(args)))
-	}
-	if filter.RepeatType != "" {
-		args = append(args, filter.RepeatType)
-		conditions = append(conditions, fmt.Sprintf("repeat_type = $%d", len(args)))
-	}
-	if filter.Search != "" {
-		args = append(args, "%"+filter.Search+"%")
-		args = append(args, "%"+filter.Search+"%")
-		conditions = append(conditions, fmt.Sprintf("(title ILIKE $%d OR description ILIKE $%d)", len(args)-1, len(args)))
-	}
-	if filter.StartAfter != nil {
-		args = append(args, *filter.StartAfter)
-		conditions = append(conditions, fmt.Sprintf("start_time >= $%d", len(args)))
-	}
-	if filter.StartBefore != nil {
-		args = append(args, *filter.StartBefore)
-		conditions = append(conditions, fmt.Sprintf("start_time < $%d", len(args)))
-	}
-
-	query := `SELECT * FROM schedules`
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
-	}
+	query := `SELECT * FROM schedules WHERE ` + strings.Join(conditions, " AND ")
 
 	// Sorting whitelist
 	allowedSortColumns := map[string]bool{
@@ -142,10 +144,7 @@ func (r *PostgresRepo) GetAll(ctx context.Context, page, limit int, filter dto.S
 	}
 
 	// Count query
-	countQuery := `SELECT COUNT(*) FROM schedules`
-	if len(conditions) > 0 {
-		countQuery += " WHERE " + strings.Join(conditions, " AND ")
-	}
+	countQuery := `SELECT COUNT(*) FROM schedules WHERE ` + strings.Join(conditions, " AND ")
 	var total int
 	if err := r.db.GetContext(ctx, &total, countQuery, args[:len(args)-2]...); err != nil {
 		return nil, 0, fmt.Errorf("failed to count schedules: %w", err)
@@ -154,9 +153,100 @@ func (r *PostgresRepo) GetAll(ctx context.Context, page, limit int, filter dto.S
 	return schedules, total, nil
 }
 
-func (r *PostgresRepo) GetByID(ctx context.Context, id string) (*domain.Schedule, error) {
+// scheduleConditions builds the WHERE conditions shared by schedule listings
+func scheduleConditions(userID string, filter dto.ScheduleFilter) ([]string, []interface{}) {
+	args := []interface{}{userID}
+	conditions := []string{"user_id = $1"}
+
+	if filter.IsDone != nil {
+		args = append(args, *filter.IsDone)
+		conditions = append(conditions, fmt.Sprintf("is_done = $%d", len(args)))
+	}
+	if filter.RepeatType != "" {
+		args = append(args, filter.RepeatType)
+		conditions = append(conditions, fmt.Sprintf("repeat_type = $%d", len(args)))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		args = append(args, "%"+filter.Search+"%")
+		conditions = append(conditions, fmt.Sprintf("(title ILIKE $%d OR description ILIKE $%d)", len(args)-1, len(args)))
+	}
+	if filter.StartAfter != nil {
+		args = append(args, *filter.StartAfter)
+		conditions = append(conditions, fmt.Sprintf("start_time >= $%d", len(args)))
+	}
+	if filter.StartBefore != nil {
+		args = append(args, *filter.StartBefore)
+		conditions = append(conditions, fmt.Sprintf("start_time < $%d", len(args)))
+	}
+	return conditions, args
+}
+
+// GetOneOffs returns every non-recurring schedule matching filter, unpaginated
+func (r *PostgresRepo) GetOneOffs(ctx context.Context, userID string, filter dto.ScheduleFilter) ([]domain.Schedule, error) {
+	conditions, args := scheduleConditions(userID, filter)
+	conditions = append(conditions, "rrule = ''")
+
+	var schedules []domain.Schedule
+	query := `SELECT * FROM schedules WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY start_time ASC`
+	if err := r.db.SelectContext(ctx, &schedules, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch one-off schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetSeriesInRange returns the recurring series of the user that can have
+// occurrences starting in [from, to)
+func (r *PostgresRepo) GetSeriesInRange(ctx context.Context, userID string, from, to time.Time) ([]domain.Schedule, error) {
+	var schedules []domain.Schedule
+	err := r.db.SelectContext(ctx, &schedules, `
+		SELECT * FROM schedules
+		WHERE user_id = $1 AND rrule <> ''
+		AND start_time < $3
+		AND (repeat_until IS NULL OR repeat_until >= $2)
+		ORDER BY start_time ASC`, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recurring schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetExceptions returns the exceptions of the given series whose original or
+// overridden start falls in [from, to)
+func (r *PostgresRepo) GetExceptions(ctx context.Context, scheduleIDs []string, from, to time.Time) ([]domain.ScheduleException, error) {
+	var exceptions []domain.ScheduleException
+	if len(scheduleIDs) == 0 {
+		return exceptions, nil
+	}
+	err := r.db.SelectContext(ctx, &exceptions, `
+		SELECT * FROM schedule_exceptions
+		WHERE schedule_id = ANY($1)
+		AND ((occurrence_start >= $2 AND occurrence_start < $3)
+			OR (start_time >= $2 AND start_time < $3))`, pq.Array(scheduleIDs), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedule exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+// GetException returns the exception of one occurrence, or sql.ErrNoRows
+func (r *PostgresRepo) GetException(ctx context.Context, scheduleID string, occurrenceStart time.Time) (*domain.ScheduleException, error) {
+	var exc domain.ScheduleException
+	err := r.db.GetContext(ctx, &exc, `
+		SELECT * FROM schedule_exceptions
+		WHERE schedule_id = $1 AND occurrence_start = $2`, scheduleID, occurrenceStart)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get schedule exception failed: %w", err)
+	}
+	return &exc, nil
+}
+
+func (r *PostgresRepo) GetByID(ctx context.Context, userID, id string) (*domain.Schedule, error) {
 	var schedule domain.Schedule
-	err := r.db.GetContext(ctx, &schedule, `SELECT * FROM schedules WHERE id = $1`, id)
+	err := r.db.GetContext(ctx, &schedule, `SELECT * FROM schedules WHERE id = $1 AND user_id = $2`, id, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, err
@@ -166,8 +256,8 @@ func (r *PostgresRepo) GetByID(ctx context.Context, id string) (*domain.Schedule
 	return &schedule, nil
 }
 
-func (r *PostgresRepo) DeleteByID(ctx context.Context, id string) error {
-	res, err := r.db.ExecContext(ctx, `DELETE FROM schedules WHERE id = $1`, id)
+func (r *PostgresRepo) DeleteByID(ctx context.Context, userID, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM schedules WHERE id = $1 AND user_id = $2`, id, userID)
 	if err != nil {
 		return fmt.Errorf("delete by id failed: %w", err)
 	}
@@ -178,36 +268,559 @@ func (r *PostgresRepo) DeleteByID(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *PostgresRepo) DeleteAll(ctx context.Context) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM schedules`)
+// DeleteAll removes every schedule owned by the user
+func (r *PostgresRepo) DeleteAll(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM schedules WHERE user_id = $1`, userID)
 	if err != nil {
 		return fmt.Errorf("delete all failed: %w", err)
 	}
 	return nil
 }
 
-func (r *PostgresRepo) GetRepeatingSchedules(ctx context.Context) ([]domain.Schedule, error) {
+// SaveException creates or replaces the exception of one occurrence
+func (r *PostgresRepo) SaveException(ctx context.Context, exc domain.ScheduleException) error {
+	return r.SaveExceptions(ctx, []domain.ScheduleException{exc})
+}
+
+// SaveExceptions creates or replaces several exceptions in one transaction
+func (r *PostgresRepo) SaveExceptions(ctx context.Context, exceptions []domain.ScheduleException) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := saveExceptions(ctx, tx, exceptions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func saveExceptions(ctx context.Context, tx *sqlx.Tx, exceptions []domain.ScheduleException) error {
+	for _, exc := range exceptions {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO schedule_exceptions
+				(schedule_id, occurrence_start, title, description, start_time, end_time, is_done, cancelled, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP)
+			ON CONFLICT (schedule_id, occurrence_start) DO UPDATE SET
+				title = EXCLUDED.title,
+				description = EXCLUDED.description,
+				start_time = EXCLUDED.start_time,
+				end_time = EXCLUDED.end_time,
+				is_done = EXCLUDED.is_done,
+				cancelled = EXCLUDED.cancelled,
+				updated_at = EXCLUDED.updated_at`,
+			exc.ScheduleID, exc.OccurrenceStart, exc.Title, exc.Description,
+			exc.StartTime, exc.EndTime, exc.IsDone, exc.Cancelled)
+		if err != nil {
+			return fmt.Errorf("save schedule exception failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetSeriesRows returns every row of a series, oldest first, or
+// sql.ErrNoRows when the series does not exist
+func (r *PostgresRepo) GetSeriesRows(ctx context.Context, userID, seriesID string) ([]domain.Schedule, error) {
 	var schedules []domain.Schedule
-	query := `
-        SELECT * FROM schedules
-        WHERE repeat_type != 'none'
-        AND (repeat_until IS NULL OR repeat_until > NOW())
-        ORDER BY start_time ASC
-    `
-	if err := r.db.SelectContext(ctx, &schedules, query); err != nil {
-		return nil, fmt.Errorf("failed to fetch repeating schedules: %w", err)
+	err := r.db.SelectContext(ctx, &schedules, `
+		SELECT * FROM schedules
+		WHERE user_id = $1 AND series_id = $2
+		ORDER BY start_time ASC`, userID, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch series: %w", err)
+	}
+	if len(schedules) == 0 {
+		return nil, sql.ErrNoRows
 	}
 	return schedules, nil
 }
 
-func (r *PostgresRepo) ExistsByStartTime(ctx context.Context, title string, start time.Time) (bool, error) {
-	var count int
-	err := r.db.GetContext(ctx, &count, `
-        SELECT COUNT(*) FROM schedules
-        WHERE title = $1 AND start_time = $2
-    `, title, start)
+// ApplySeriesChange saves an edit spanning several rows of a series in one
+// transaction. Exceptions are moved before rows are deleted so that moving
+// them off a deleted row keeps them.
+func (r *PostgresRepo) ApplySeriesChange(ctx context.Context, userID string, change domain.SeriesChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := applySeriesChange(ctx, tx, userID, change); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func applySeriesChange(ctx context.Context, tx *sqlx.Tx, userID string, change domain.SeriesChange) error {
+	if len(change.Insert) > 0 {
+		if err := insertSchedules(ctx, tx, change.Insert); err != nil {
+			return err
+		}
+	}
+	for _, s := range change.Update {
+		if err := updateSchedule(ctx, tx, userID, s.ID, s); err != nil {
+			return err
+		}
+	}
+	if err := saveExceptions(ctx, tx, change.Exceptions); err != nil {
+		return err
+	}
+	for _, m := range change.Move {
+		if err := moveExceptions(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+	if len(change.Delete) > 0 {
+		_, err := tx.ExecContext(ctx, `DELETE FROM schedules WHERE user_id = $1 AND id = ANY($2)`,
+			userID, pq.Array(change.Delete))
+		if err != nil {
+			return fmt.Errorf("delete series rows failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// moveExceptions rekeys exceptions one by one. The key is checked after
+// every row, so a single UPDATE fails when a series moves onto its own next
+// occurrence; visiting them in the direction of the shift never moves one
+// onto an exception that has not moved yet.
+func moveExceptions(ctx context.Context, tx *sqlx.Tx, m domain.ExceptionMove) error {
+	order := "ASC"
+	if m.Shift > 0 {
+		order = "DESC"
+	}
+	var starts []time.Time
+	err := tx.SelectContext(ctx, &starts, `
+		SELECT occurrence_start FROM schedule_exceptions
+		WHERE schedule_id = $1 AND occurrence_start >= $2
+		ORDER BY occurrence_start `+order, m.FromID, m.Since)
+	if err != nil {
+		return fmt.Errorf("move schedule exceptions failed: %w", err)
+	}
+
+	shift := m.Shift.Seconds()
+	for _, start := range starts {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE schedule_exceptions
+			SET schedule_id = $1,
+			    occurrence_start = occurrence_start + make_interval(secs => $2),
+			    start_time = start_time + make_interval(secs => $2),
+			    end_time = end_time + make_interval(secs => $2)
+			WHERE schedule_id = $3 AND occurrence_start = $4`,
+			m.ToID, shift, m.FromID, start)
+		if err != nil {
+			return fmt.Errorf("move schedule exceptions failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRepo) SavePreview(ctx context.Context, preview domain.SchedulePreview) error {
+	items, err := json.Marshal(preview.Schedules)
+	if err != nil {
+		return fmt.Errorf("encode preview items failed: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO schedule_previews (token, user_id, provider, items, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		preview.Token, preview.UserID, preview.Provider, items, preview.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("save preview failed: %w", err)
+	}
+	return nil
+}
+
+// GetPreview returns a preview that has not expired yet, or sql.ErrNoRows
+func (r *PostgresRepo) GetPreview(ctx context.Context, userID, token string) (*domain.SchedulePreview, error) {
+	var row struct {
+		Token     string    `db:"token"`
+		UserID    string    `db:"user_id"`
+		Provider  string    `db:"provider"`
+		Items     []byte    `db:"items"`
+		CreatedAt time.Time `db:"created_at"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		SELECT token, user_id, provider, items, created_at, expires_at FROM schedule_previews
+		WHERE token = $1 AND user_id = $2 AND expires_at > $3`, token, userID, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get preview failed: %w", err)
+	}
+
+	preview := &domain.SchedulePreview{
+		Token:     row.Token,
+		UserID:    row.UserID,
+		Provider:  row.Provider,
+		CreatedAt: row.CreatedAt,
+		ExpiresAt: row.ExpiresAt,
+	}
+	if err := json.Unmarshal(row.Items, &preview.Schedules); err != nil {
+		return nil, fmt.Errorf("decode preview items failed: %w", err)
+	}
+	return preview, nil
+}
+
+// CommitPreview consumes the preview and saves the schedules in one
+// transaction, so a token can only ever be committed once.
+func (r *PostgresRepo) CommitPreview(ctx context.Context, userID, token string, schedules []domain.Schedule) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM schedule_previews
+		WHERE token = $1 AND user_id = $2 AND expires_at > $3`, token, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("consume preview failed: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if len(schedules) > 0 {
+		if err := insertSchedules(ctx, tx, schedules); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) DeleteExpiredPreviews(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM schedule_previews WHERE expires_at <= $1`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired previews failed: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	return rows, nil
+}
+
+func (r *PostgresRepo) SaveReplan(ctx context.Context, proposal domain.ReplanProposal) error {
+	diff, err := json.Marshal(proposal.Diff)
 	if err != nil {
-		return false, err
+		return fmt.Errorf("encode replan diff failed: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO schedule_replans (token, user_id, day, instruction, provider, diff, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		proposal.Token, proposal.UserID, proposal.Day.Format("2006-01-02"), proposal.Instruction,
+		proposal.Provider, diff, proposal.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("save replan failed: %w", err)
+	}
+	return nil
+}
+
+// GetReplan returns a replan that has not expired yet, or sql.ErrNoRows.
+// Day is midnight of the replanned date in loc.
+func (r *PostgresRepo) GetReplan(ctx context.Context, userID, token string, loc *time.Location) (*domain.ReplanProposal, error) {
+	var row struct {
+		Token       string    `db:"token"`
+		UserID      string    `db:"user_id"`
+		Day         string    `db:"day"`
+		Instruction string    `db:"instruction"`
+		Provider    string    `db:"provider"`
+		Diff        []byte    `db:"diff"`
+		CreatedAt   time.Time `db:"created_at"`
+		ExpiresAt   time.Time `db:"expires_at"`
 	}
-	return count > 0, nil
+	err := r.db.GetContext(ctx, &row, `
+		SELECT token, user_id, to_char(day, 'YYYY-MM-DD') AS day, instruction, provider, diff, created_at, expires_at
+		FROM schedule_replans
+		WHERE token = $1 AND user_id = $2 AND expires_at > $3`, token, userID, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get replan failed: %w", err)
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", row.Day, loc)
+	if err != nil {
+		return nil, fmt.Errorf("decode replan day failed: %w", err)
+	}
+	proposal := &domain.ReplanProposal{
+		Token:       row.Token,
+		UserID:      row.UserID,
+		Day:         day,
+		Instruction: row.Instruction,
+		Provider:    row.Provider,
+		CreatedAt:   row.CreatedAt,
+		ExpiresAt:   row.ExpiresAt,
+	}
+	if err := json.Unmarshal(row.Diff, &proposal.Diff); err != nil {
+		return nil, fmt.Errorf("decode replan diff failed: %w", err)
+	}
+	return proposal, nil
+}
+
+// DeleteReplan consumes a replan that has not expired yet, returning
+// sql.ErrNoRows when there is none, so a replan is only ever applied once
+func (r *PostgresRepo) DeleteReplan(ctx context.Context, userID, token string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM schedule_replans
+		WHERE token = $1 AND user_id = $2 AND expires_at > $3`, token, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("delete replan failed: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptReplan consumes a replan and saves the change it resolved to in one
+// transaction, so a failed change leaves the replan to be retried.
+func (r *PostgresRepo) AcceptReplan(ctx context.Context, userID, token string, change domain.SeriesChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM schedule_replans
+		WHERE token = $1 AND user_id = $2 AND expires_at > $3`, token, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("consume replan failed: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := applySeriesChange(ctx, tx, userID, change); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) DeleteExpiredReplans(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM schedule_replans WHERE expires_at <= $1`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired replans failed: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	return rows, nil
+}
+
+type profileRow struct {
+	UserID       string    `db:"user_id"`
+	Occupation   string    `db:"occupation"`
+	WakeTime     string    `db:"wake_time"`
+	SleepTime    string    `db:"sleep_time"`
+	Timezone     string    `db:"timezone"`
+	WorkStart    string    `db:"work_start"`
+	WorkEnd      string    `db:"work_end"`
+	Habits       []byte    `db:"habits"`
+	BlockedTimes []byte    `db:"blocked_times"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+// GetProfile returns the stored profile, or sql.ErrNoRows if none was saved
+func (r *PostgresRepo) GetProfile(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	var row profileRow
+	if err := r.db.GetContext(ctx, &row, `SELECT * FROM user_profiles WHERE user_id = $1`, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get profile failed: %w", err)
+	}
+
+	profile := &domain.UserProfile{
+		UserID:     row.UserID,
+		Occupation: row.Occupation,
+		WakeTime:   row.WakeTime,
+		SleepTime:  row.SleepTime,
+		Timezone:   row.Timezone,
+		WorkStart:  row.WorkStart,
+		WorkEnd:    row.WorkEnd,
+		UpdatedAt:  row.UpdatedAt,
+	}
+	if err := json.Unmarshal(row.Habits, &profile.Habits); err != nil {
+		return nil, fmt.Errorf("decode habits failed: %w", err)
+	}
+	if err := json.Unmarshal(row.BlockedTimes, &profile.BlockedTimes); err != nil {
+		return nil, fmt.Errorf("decode blocked times failed: %w", err)
+	}
+	return profile, nil
+}
+
+// SaveProfile creates or replaces the profile
+func (r *PostgresRepo) SaveProfile(ctx context.Context, profile domain.UserProfile) error {
+	habits, err := json.Marshal(profile.Habits)
+	if err != nil {
+		return fmt.Errorf("encode habits failed: %w", err)
+	}
+	blocked, err := json.Marshal(profile.BlockedTimes)
+	if err != nil {
+		return fmt.Errorf("encode blocked times failed: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO user_profiles
+			(user_id, occupation, wake_time, sleep_time, timezone, work_start, work_end, habits, blocked_times, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id) DO UPDATE SET
+			occupation = EXCLUDED.occupation,
+			wake_time = EXCLUDED.wake_time,
+			sleep_time = EXCLUDED.sleep_time,
+			timezone = EXCLUDED.timezone,
+			work_start = EXCLUDED.work_start,
+			work_end = EXCLUDED.work_end,
+			habits = EXCLUDED.habits,
+			blocked_times = EXCLUDED.blocked_times,
+			updated_at = EXCLUDED.updated_at`,
+		profile.UserID, profile.Occupation, profile.WakeTime, profile.SleepTime, profile.Timezone,
+		profile.WorkStart, profile.WorkEnd, habits, blocked, profile.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save profile failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) CreateUser(ctx context.Context, user domain.User) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO users (id, name, email, created_at) VALUES ($1, $2, $3, $4)`,
+		user.ID, user.Name, user.Email, user.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return domain.ErrEmailTaken
+		}
+		return fmt.Errorf("create user failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	var user domain.User
+	err := r.db.GetContext(ctx, &user, `SELECT id, name, email, created_at FROM users WHERE id = $1`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get user failed: %w", err)
+	}
+	return &user, nil
+}
+
+func (r *PostgresRepo) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create api key failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.GetContext(ctx, &key, `SELECT * FROM api_keys WHERE key_hash = $1`, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get api key failed: %w", err)
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByID is not scoped to a user so callers can tell "not found"
+// apart from "owned by someone else"
+func (r *PostgresRepo) GetAPIKeyByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.GetContext(ctx, &key, `SELECT * FROM api_keys WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get api key failed: %w", err)
+	}
+	return &key, nil
+}
+
+func (r *PostgresRepo) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := r.db.SelectContext(ctx, &keys, `
+		SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys failed: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *PostgresRepo) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = $1
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`, at, id, userID)
+	if err != nil {
+		return fmt.Errorf("revoke api key failed: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresRepo) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, at, id)
+	if err != nil {
+		return fmt.Errorf("touch api key failed: %w", err)
+	}
+	return nil
+}
+
+// WithJobLock runs fn while holding a Postgres advisory lock named after the
+// job, so only one replica runs it at a time. It returns false without
+// calling fn when another session holds the lock. The lock is held on a
+// dedicated connection because advisory locks belong to the session.
+func (r *PostgresRepo) WithJobLock(ctx context.Context, job string, fn func(context.Context) error) (bool, error) {
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection for job lock: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock(hashtext($1))`, job); err != nil {
+		return false, fmt.Errorf("failed to take job lock: %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even after a timeout
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock(hashtext($1))`, job); err != nil {
+			// Closing the connection ends the session, which releases the lock too
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	return true, fn(ctx)
 }
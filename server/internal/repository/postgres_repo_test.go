@@ -0,0 +1,48 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"murim-helper/internal/repository"
+	"murim-helper/internal/repository/repotest"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// TestPostgresRepo runs the contract suite against the database at
+// POSTGRES_TEST_CONN, migrating it first. Every subtest works on its own
+// user, so the database does not need to be empty.
+func TestPostgresRepo(t *testing.T) {
+	conn := os.Getenv("POSTGRES_TEST_CONN")
+	if conn == "" {
+		t.Skip("POSTGRES_TEST_CONN is not set")
+	}
+
+	repo, err := repository.NewPostgresRepo(conn, repository.PoolConfig{})
+	if err != nil {
+		t.Fatalf("NewPostgresRepo: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := repository.PingWithRetry(ctx, repo); err != nil {
+		t.Fatalf("PingWithRetry: %v", err)
+	}
+
+	m, err := repo.Migrator(ctx)
+	if err != nil {
+		t.Fatalf("Migrator: %v", err)
+	}
+	err = m.Up()
+	m.Close()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	repotest.TestRepository(t, func(t *testing.T) repository.Repository {
+		return repo
+	})
+}
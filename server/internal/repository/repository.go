@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"sync"
+	"time"
+)
+
+// ScheduleRepository stores schedules with their exceptions, previews and
+// replans. Lookups of a single row return sql.ErrNoRows when it does not
+// exist.
+type ScheduleRepository interface {
+	ProfileRepository
+
+	SaveMany(ctx context.Context, schedules []domain.Schedule) error
+	Update(ctx context.Context, userID, id string, updated domain.Schedule) error
+	GetAll(ctx context.Context, userID string, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error)
+	GetOneOffs(ctx context.Context, userID string, filter dto.ScheduleFilter) ([]domain.Schedule, error)
+	GetSeriesInRange(ctx context.Context, userID string, from, to time.Time) ([]domain.Schedule, error)
+	GetByID(ctx context.Context, userID, id string) (*domain.Schedule, error)
+	DeleteByID(ctx context.Context, userID, id string) error
+	DeleteAll(ctx context.Context, userID string) error
+
+	GetExceptions(ctx context.Context, scheduleIDs []string, from, to time.Time) ([]domain.ScheduleException, error)
+	GetException(ctx context.Context, scheduleID string, occurrenceStart time.Time) (*domain.ScheduleException, error)
+	SaveException(ctx context.Context, exc domain.ScheduleException) error
+	SaveExceptions(ctx context.Context, exceptions []domain.ScheduleException) error
+	GetSeriesRows(ctx context.Context, userID, seriesID string) ([]domain.Schedule, error)
+	ApplySeriesChange(ctx context.Context, userID string, change domain.SeriesChange) error
+
+	SavePreview(ctx context.Context, preview domain.SchedulePreview) error
+	GetPreview(ctx context.Context, userID, token string) (*domain.SchedulePreview, error)
+	CommitPreview(ctx context.Context, userID, token string, schedules []domain.Schedule) error
+	DeleteExpiredPreviews(ctx context.Context) (int64, error)
+
+	SaveReplan(ctx context.Context, proposal domain.ReplanProposal) error
+	GetReplan(ctx context.Context, userID, token string, loc *time.Location) (*domain.ReplanProposal, error)
+	DeleteReplan(ctx context.Context, userID, token string) error
+	AcceptReplan(ctx context.Context, userID, token string, change domain.SeriesChange) error
+	DeleteExpiredReplans(ctx context.Context) (int64, error)
+}
+
+// ProfileRepository stores the routine of each user
+type ProfileRepository interface {
+	GetProfile(ctx context.Context, userID string) (*domain.UserProfile, error)
+	SaveProfile(ctx context.Context, profile domain.UserProfile) error
+}
+
+// UserRepository stores users and their API keys
+type UserRepository interface {
+	CreateUser(ctx context.Context, user domain.User) error
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	CreateAPIKey(ctx context.Context, key domain.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	GetAPIKeyByID(ctx context.Context, id string) (*domain.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+}
+
+// Repository is everything the server needs from a storage backend
+type Repository interface {
+	ScheduleRepository
+	UserRepository
+
+	// WithJobLock runs fn unless another run of the job holds the lock,
+	// reporting whether fn ran
+	WithJobLock(ctx context.Context, job string, fn func(context.Context) error) (bool, error)
+
+	Pinger
+	// Stats reports on the connection pool
+	Stats() sql.DBStats
+}
+
+var (
+	_ Repository = (*PostgresRepo)(nil)
+	_ Repository = (*SQLiteRepo)(nil)
+	_ Repository = (*MemoryRepo)(nil)
+)
+
+// jobLocks lets one run of each job through at a time, for backends that
+// are only ever used by a single process
+type jobLocks struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// run calls fn unless the job is already running, returning false without
+// calling it when it is
+func (l *jobLocks) run(ctx context.Context, job string, fn func(context.Context) error) (bool, error) {
+	l.mu.Lock()
+	if l.running[job] {
+		l.mu.Unlock()
+		return false, nil
+	}
+	if l.running == nil {
+		l.running = map[string]bool{}
+	}
+	l.running[job] = true
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		delete(l.running, job)
+		l.mu.Unlock()
+	}()
+
+	return true, fn(ctx)
+}
@@ -0,0 +1,792 @@
+// Package repotest checks that a storage backend honours the contract of
+// repository.Repository, with the semantics PostgresRepo defines. Every
+// backend's tests should pass it:
+//
+//	func TestSQLiteRepo(t *testing.T) {
+//		repotest.TestRepository(t, func(t *testing.T) repository.Repository {
+//			repo, err := repository.NewSQLiteRepo(t.TempDir() + "/db")
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return repo
+//		})
+//	}
+//
+// Each subtest works on a freshly created user, so the suite can also run
+// against a shared database.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"murim-helper/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// base is a fixed point in time, whole seconds so every backend stores it
+// exactly
+var base = time.Date(2025, 8, 4, 9, 0, 0, 0, time.UTC)
+
+// TestRepository runs the contract suite. newRepo is called once per
+// subtest.
+func TestRepository(t *testing.T, newRepo func(t *testing.T) repository.Repository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.Repository)
+	}{
+		{"Schedules", testSchedules},
+		{"GetAll", testGetAll},
+		{"Series", testSeries},
+		{"Exceptions", testExceptions},
+		{"ApplySeriesChange", testApplySeriesChange},
+		{"ShiftSeries", testShiftSeries},
+		{"Previews", testPreviews},
+		{"Replans", testReplans},
+		{"AcceptReplan", testAcceptReplan},
+		{"Profiles", testProfiles},
+		{"Users", testUsers},
+		{"APIKeys", testAPIKeys},
+		{"Jobs", testJobs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+// newUser creates a user with a unique email
+func newUser(t *testing.T, repo repository.Repository) string {
+	t.Helper()
+	user := domain.User{ID: uuid.NewString(), Name: "Tester", CreatedAt: base}
+	user.Email = user.ID + "@example.com"
+	if err := repo.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user.ID
+}
+
+// schedule returns a one-hour one-off schedule starting hours after base
+func schedule(userID, title string, hours int) domain.Schedule {
+	start := base.Add(time.Duration(hours) * time.Hour)
+	return domain.Schedule{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		Title:      title,
+		StartTime:  start,
+		EndTime:    start.Add(time.Hour),
+		RepeatType: "none",
+	}
+}
+
+// series returns a daily recurring schedule starting hours after base
+func series(userID, title string, hours int) domain.Schedule {
+	s := schedule(userID, title, hours)
+	s.RRule = "FREQ=DAILY"
+	s.RepeatType = "daily"
+	return s
+}
+
+func save(t *testing.T, repo repository.Repository, schedules ...domain.Schedule) {
+	t.Helper()
+	if err := repo.SaveMany(context.Background(), schedules); err != nil {
+		t.Fatalf("SaveMany: %v", err)
+	}
+}
+
+func titles(schedules []domain.Schedule) []string {
+	out := make([]string, len(schedules))
+	for i, s := range schedules {
+		out[i] = s.Title
+	}
+	return out
+}
+
+func expectTitles(t *testing.T, what string, got []domain.Schedule, want ...string) {
+	t.Helper()
+	gotTitles := titles(got)
+	if len(gotTitles) != len(want) {
+		t.Fatalf("%s: got %q, want %q", what, gotTitles, want)
+	}
+	for i := range want {
+		if gotTitles[i] != want[i] {
+			t.Fatalf("%s: got %q, want %q", what, gotTitles, want)
+		}
+	}
+}
+
+func expectNoRows(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("%s: got error %v, want sql.ErrNoRows", what, err)
+	}
+}
+
+func testSchedules(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	other := newUser(t, repo)
+
+	s := schedule(userID, "Write", 0)
+	s.Description = "chapter one"
+	until := base.AddDate(0, 1, 0)
+	s.RepeatUntil = &until
+	save(t, repo, s)
+
+	got, err := repo.GetByID(ctx, userID, s.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Title != "Write" || got.Description != "chapter one" || !got.StartTime.Equal(s.StartTime) ||
+		!got.EndTime.Equal(s.EndTime) || got.RepeatUntil == nil || !got.RepeatUntil.Equal(until) {
+		t.Fatalf("GetByID returned %+v, want %+v", got, s)
+	}
+	if got.SeriesID != s.ID {
+		t.Errorf("SeriesID defaults to the ID: got %q, want %q", got.SeriesID, s.ID)
+	}
+	if got.Timezone != "UTC" {
+		t.Errorf("Timezone defaults to UTC: got %q", got.Timezone)
+	}
+
+	_, err = repo.GetByID(ctx, other, s.ID)
+	expectNoRows(t, "GetByID of another user's schedule", err)
+	_, err = repo.GetByID(ctx, userID, uuid.NewString())
+	expectNoRows(t, "GetByID of a missing schedule", err)
+
+	if err := repo.SaveMany(ctx, []domain.Schedule{s}); err == nil {
+		t.Error("SaveMany with a duplicate ID succeeded")
+	}
+
+	s.Title = "Rewrite"
+	s.IsDone = true
+	s.RepeatUntil = nil
+	if err := repo.Update(ctx, userID, s.ID, s); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ = repo.GetByID(ctx, userID, s.ID)
+	if got.Title != "Rewrite" || !got.IsDone || got.RepeatUntil != nil {
+		t.Errorf("Update not saved: %+v", got)
+	}
+	expectNoRows(t, "Update of another user's schedule", repo.Update(ctx, other, s.ID, s))
+
+	expectNoRows(t, "DeleteByID of another user's schedule", repo.DeleteByID(ctx, other, s.ID))
+	if err := repo.DeleteByID(ctx, userID, s.ID); err != nil {
+		t.Fatalf("DeleteByID: %v", err)
+	}
+	expectNoRows(t, "DeleteByID twice", repo.DeleteByID(ctx, userID, s.ID))
+
+	save(t, repo, schedule(userID, "A", 0), schedule(userID, "B", 1), schedule(other, "C", 2))
+	if err := repo.DeleteAll(ctx, userID); err != nil {
+		t.Fatalf("DeleteAll: %v", err)
+	}
+	if _, total, _ := repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{}); total != 0 {
+		t.Errorf("DeleteAll left %d schedules", total)
+	}
+	if _, total, _ := repo.GetAll(ctx, other, 1, 10, dto.ScheduleFilter{}); total != 1 {
+		t.Errorf("DeleteAll removed schedules of another user")
+	}
+}
+
+func testGetAll(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+
+	gym := schedule(userID, "Gym", 2)
+	gym.Description = "leg day"
+	gym.IsDone = true
+	standup := series(userID, "Standup", 0)
+	lunch := schedule(userID, "Lunch", 3)
+	lunch.Description = "with the team"
+	review := schedule(userID, "Code review", 1)
+	review.EndTime = review.StartTime.Add(4 * time.Hour)
+	save(t, repo, gym, standup, lunch, review)
+	save(t, repo, schedule(newUser(t, repo), "Someone else's", 0))
+
+	list, total, err := repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if total != 4 {
+		t.Errorf("total: got %d, want 4", total)
+	}
+	expectTitles(t, "default order", list, "Standup", "Code review", "Gym", "Lunch")
+
+	list, _, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{SortBy: "end_time", SortOrder: "DESC"})
+	expectTitles(t, "end_time desc", list, "Code review", "Lunch", "Gym", "Standup")
+	list, _, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{SortBy: "title"})
+	expectTitles(t, "title", list, "Code review", "Gym", "Lunch", "Standup")
+	list, _, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{SortBy: "id; DROP TABLE schedules"})
+	expectTitles(t, "unknown sort column", list, "Standup", "Code review", "Gym", "Lunch")
+
+	list, total, _ = repo.GetAll(ctx, userID, 2, 3, dto.ScheduleFilter{})
+	if total != 4 {
+		t.Errorf("paged total: got %d, want 4", total)
+	}
+	expectTitles(t, "second page", list, "Lunch")
+	list, _, _ = repo.GetAll(ctx, userID, 3, 3, dto.ScheduleFilter{})
+	expectTitles(t, "page past the end", list)
+
+	done := true
+	list, total, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{IsDone: &done})
+	expectTitles(t, "is_done", list, "Gym")
+	if total != 1 {
+		t.Errorf("filtered total: got %d, want 1", total)
+	}
+	list, _, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{RepeatType: "daily"})
+	expectTitles(t, "repeat_type", list, "Standup")
+	list, _, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{Search: "LEG"})
+	expectTitles(t, "search ignores case and matches descriptions", list, "Gym")
+	list, _, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{Search: "the"})
+	expectTitles(t, "search matches inside words", list, "Lunch")
+
+	after, before := base.Add(time.Hour), base.Add(3*time.Hour)
+	list, _, _ = repo.GetAll(ctx, userID, 1, 10, dto.ScheduleFilter{StartAfter: &after, StartBefore: &before})
+	expectTitles(t, "start_after is inclusive and start_before exclusive", list, "Code review", "Gym")
+
+	list, _ = repo.GetOneOffs(ctx, userID, dto.ScheduleFilter{StartAfter: &base})
+	expectTitles(t, "GetOneOffs", list, "Code review", "Gym", "Lunch")
+}
+
+func testSeries(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	other := newUser(t, repo)
+
+	daily := series(userID, "Daily", 0)
+	ended := series(userID, "Ended", 1)
+	until := base.AddDate(0, 0, 2)
+	ended.RepeatUntil = &until
+	later := series(userID, "Later", 24*10)
+	save(t, repo, daily, ended, later, schedule(userID, "One-off", 2), series(other, "Other", 0))
+
+	from, to := base.AddDate(0, 0, 5), base.AddDate(0, 0, 6)
+	list, err := repo.GetSeriesInRange(ctx, userID, from, to)
+	if err != nil {
+		t.Fatalf("GetSeriesInRange: %v", err)
+	}
+	expectTitles(t, "GetSeriesInRange", list, "Daily")
+
+	list, _ = repo.GetSeriesInRange(ctx, userID, base, to)
+	expectTitles(t, "GetSeriesInRange with repeat_until in range", list, "Daily", "Ended")
+
+	tail := series(userID, "Daily", 24*3)
+	tail.SeriesID = daily.ID
+	save(t, repo, tail)
+	list, err = repo.GetSeriesRows(ctx, userID, daily.ID)
+	if err != nil {
+		t.Fatalf("GetSeriesRows: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != daily.ID || list[1].ID != tail.ID {
+		t.Errorf("GetSeriesRows: got %d rows, want the head then the tail", len(list))
+	}
+	_, err = repo.GetSeriesRows(ctx, other, daily.ID)
+	expectNoRows(t, "GetSeriesRows of another user's series", err)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testExceptions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	daily := series(userID, "Daily", 0)
+	save(t, repo, daily)
+
+	second := base.AddDate(0, 0, 1)
+	_, err := repo.GetException(ctx, daily.ID, second)
+	expectNoRows(t, "GetException before saving", err)
+
+	exc := domain.ScheduleException{ScheduleID: daily.ID, OccurrenceStart: second, IsDone: true}
+	if err := repo.SaveException(ctx, exc); err != nil {
+		t.Fatalf("SaveException: %v", err)
+	}
+	got, err := repo.GetException(ctx, daily.ID, second)
+	if err != nil {
+		t.Fatalf("GetException: %v", err)
+	}
+	if !got.IsDone || got.Cancelled || got.Title != nil || got.StartTime != nil {
+		t.Errorf("GetException returned %+v", got)
+	}
+
+	// Saving again replaces the exception
+	moved := second.AddDate(0, 0, 5)
+	exc = domain.ScheduleException{
+		ScheduleID:      daily.ID,
+		OccurrenceStart: second,
+		Title:           ptr("Moved"),
+		StartTime:       &moved,
+		EndTime:         ptr(moved.Add(time.Hour)),
+	}
+	cancelled := domain.ScheduleException{ScheduleID: daily.ID, OccurrenceStart: base.AddDate(0, 0, 2), Cancelled: true}
+	if err := repo.SaveExceptions(ctx, []domain.ScheduleException{exc, cancelled}); err != nil {
+		t.Fatalf("SaveExceptions: %v", err)
+	}
+	got, _ = repo.GetException(ctx, daily.ID, second)
+	if got.IsDone || got.Title == nil || *got.Title != "Moved" || got.StartTime == nil || !got.StartTime.Equal(moved) {
+		t.Errorf("SaveExceptions did not replace the exception: %+v", got)
+	}
+
+	list, err := repo.GetExceptions(ctx, []string{daily.ID}, second, second.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetExceptions: %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("GetExceptions by original start: got %d, want 1", len(list))
+	}
+	list, _ = repo.GetExceptions(ctx, []string{daily.ID}, moved, moved.AddDate(0, 0, 1))
+	if len(list) != 1 {
+		t.Errorf("GetExceptions by overridden start: got %d, want 1", len(list))
+	}
+	list, _ = repo.GetExceptions(ctx, []string{daily.ID}, base, base.AddDate(0, 0, 3))
+	if len(list) != 2 {
+		t.Errorf("GetExceptions over the week: got %d, want 2", len(list))
+	}
+	list, _ = repo.GetExceptions(ctx, []string{uuid.NewString()}, base, base.AddDate(1, 0, 0))
+	if len(list) != 0 {
+		t.Errorf("GetExceptions of another series: got %d, want 0", len(list))
+	}
+	list, err = repo.GetExceptions(ctx, nil, base, base.AddDate(1, 0, 0))
+	if err != nil || len(list) != 0 {
+		t.Errorf("GetExceptions without series: got %d, %v", len(list), err)
+	}
+
+	if err := repo.DeleteByID(ctx, userID, daily.ID); err != nil {
+		t.Fatalf("DeleteByID: %v", err)
+	}
+	_, err = repo.GetException(ctx, daily.ID, second)
+	expectNoRows(t, "GetException after deleting the series", err)
+}
+
+func testApplySeriesChange(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	head := series(userID, "Daily", 0)
+	gone := series(userID, "Daily", 24*20)
+	gone.SeriesID = head.ID
+	save(t, repo, head, gone)
+
+	var exceptions []domain.ScheduleException
+	for day := 0; day < 4; day++ {
+		exceptions = append(exceptions, domain.ScheduleException{
+			ScheduleID:      head.ID,
+			OccurrenceStart: base.AddDate(0, 0, day),
+			IsDone:          true,
+		})
+	}
+	exceptions = append(exceptions, domain.ScheduleException{ScheduleID: gone.ID, OccurrenceStart: gone.StartTime, IsDone: true})
+	if err := repo.SaveExceptions(ctx, exceptions); err != nil {
+		t.Fatalf("SaveExceptions: %v", err)
+	}
+
+	// Split the series on the third day and move the tail an hour later
+	split := base.AddDate(0, 0, 2)
+	updated := head
+	until := split.Add(-time.Second)
+	updated.RepeatUntil = &until
+	tail := series(userID, "Daily", 24*2+1)
+	tail.SeriesID = head.ID
+	change := domain.SeriesChange{
+		Update: []domain.Schedule{updated},
+		Insert: []domain.Schedule{tail},
+		Delete: []string{gone.ID},
+		Move: []domain.ExceptionMove{
+			{FromID: head.ID, ToID: tail.ID, Since: split, Shift: time.Hour},
+			{FromID: gone.ID, ToID: tail.ID, Since: gone.StartTime, Shift: 0},
+		},
+	}
+	if err := repo.ApplySeriesChange(ctx, userID, change); err != nil {
+		t.Fatalf("ApplySeriesChange: %v", err)
+	}
+
+	rows, err := repo.GetSeriesRows(ctx, userID, head.ID)
+	if err != nil {
+		t.Fatalf("GetSeriesRows: %v", err)
+	}
+	if len(rows) != 2 || rows[0].ID != head.ID || rows[1].ID != tail.ID {
+		t.Fatalf("series rows after the change: got %d rows", len(rows))
+	}
+	if rows[0].RepeatUntil == nil || !rows[0].RepeatUntil.Equal(until) {
+		t.Errorf("head not updated: %+v", rows[0])
+	}
+
+	far := base.AddDate(0, 1, 0)
+	kept, _ := repo.GetExceptions(ctx, []string{head.ID}, base, far)
+	if len(kept) != 2 {
+		t.Errorf("exceptions left on the head: got %d, want 2", len(kept))
+	}
+	moved, _ := repo.GetExceptions(ctx, []string{tail.ID}, base, far)
+	if len(moved) != 3 {
+		t.Fatalf("exceptions moved to the tail: got %d, want 3", len(moved))
+	}
+	for _, start := range []time.Time{split.Add(time.Hour), base.AddDate(0, 0, 3).Add(time.Hour), gone.StartTime} {
+		if _, err := repo.GetException(ctx, tail.ID, start); err != nil {
+			t.Errorf("moved exception at %s: %v", start, err)
+		}
+	}
+
+	// Moving in place shifts the exceptions of the row
+	change = domain.SeriesChange{Move: []domain.ExceptionMove{{FromID: tail.ID, ToID: tail.ID, Shift: -30 * time.Minute}}}
+	if err := repo.ApplySeriesChange(ctx, userID, change); err != nil {
+		t.Fatalf("ApplySeriesChange in place: %v", err)
+	}
+	if _, err := repo.GetException(ctx, tail.ID, split.Add(30*time.Minute)); err != nil {
+		t.Errorf("exception shifted in place: %v", err)
+	}
+
+	// A failing change leaves everything as it was
+	change = domain.SeriesChange{
+		Insert: []domain.Schedule{series(userID, "Partial", 5)},
+		Update: []domain.Schedule{schedule(userID, "Missing", 0)},
+	}
+	expectNoRows(t, "ApplySeriesChange updating a missing row", repo.ApplySeriesChange(ctx, userID, change))
+	if _, err := repo.GetByID(ctx, userID, change.Insert[0].ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("failed ApplySeriesChange kept its insert: %v", err)
+	}
+}
+
+// testShiftSeries moves a series onto its own next occurrence, so every
+// exception is rekeyed onto the start of the next one
+func testShiftSeries(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	daily := series(userID, "Daily", 0)
+	save(t, repo, daily)
+
+	rescheduled := base.AddDate(0, 0, 1).Add(2 * time.Hour)
+	exceptions := []domain.ScheduleException{
+		{ScheduleID: daily.ID, OccurrenceStart: base, IsDone: true},
+		{ScheduleID: daily.ID, OccurrenceStart: base.AddDate(0, 0, 1), StartTime: &rescheduled, EndTime: ptr(rescheduled.Add(time.Hour))},
+		{ScheduleID: daily.ID, OccurrenceStart: base.AddDate(0, 0, 2), Cancelled: true},
+	}
+	if err := repo.SaveExceptions(ctx, exceptions); err != nil {
+		t.Fatalf("SaveExceptions: %v", err)
+	}
+
+	for _, shift := range []time.Duration{24 * time.Hour, -24 * time.Hour} {
+		change := domain.SeriesChange{Move: []domain.ExceptionMove{{FromID: daily.ID, ToID: daily.ID, Shift: shift}}}
+		if err := repo.ApplySeriesChange(ctx, userID, change); err != nil {
+			t.Fatalf("ApplySeriesChange shifting by %s: %v", shift, err)
+		}
+		for i := range exceptions {
+			want := &exceptions[i]
+			want.Shift(shift)
+			got, err := repo.GetException(ctx, daily.ID, want.OccurrenceStart)
+			if err != nil {
+				t.Fatalf("exception shifted by %s to %s: %v", shift, want.OccurrenceStart, err)
+			}
+			if got.IsDone != want.IsDone || got.Cancelled != want.Cancelled {
+				t.Errorf("exception shifted by %s to %s: got %+v", shift, want.OccurrenceStart, got)
+			}
+			if (want.StartTime == nil) != (got.StartTime == nil) || (want.StartTime != nil &&
+				(!got.StartTime.Equal(*want.StartTime) || !got.EndTime.Equal(*want.EndTime))) {
+				t.Errorf("override shifted by %s: got %v-%v, want %v-%v", shift, got.StartTime, got.EndTime, want.StartTime, want.EndTime)
+			}
+		}
+		list, _ := repo.GetExceptions(ctx, []string{daily.ID}, base.AddDate(0, 0, -7), base.AddDate(0, 0, 7))
+		if len(list) != 3 {
+			t.Errorf("exceptions after shifting by %s: got %d, want 3", shift, len(list))
+		}
+	}
+}
+
+func testPreviews(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	now := time.Now()
+
+	item := schedule(userID, "Planned", 0)
+	preview := domain.SchedulePreview{
+		Token:     uuid.NewString(),
+		UserID:    userID,
+		Provider:  "groq",
+		Schedules: []domain.Schedule{item},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	if err := repo.SavePreview(ctx, preview); err != nil {
+		t.Fatalf("SavePreview: %v", err)
+	}
+	got, err := repo.GetPreview(ctx, userID, preview.Token)
+	if err != nil {
+		t.Fatalf("GetPreview: %v", err)
+	}
+	if got.Provider != "groq" || len(got.Schedules) != 1 || got.Schedules[0].Title != "Planned" ||
+		!got.Schedules[0].StartTime.Equal(item.StartTime) {
+		t.Errorf("GetPreview returned %+v", got)
+	}
+	_, err = repo.GetPreview(ctx, newUser(t, repo), preview.Token)
+	expectNoRows(t, "GetPreview of another user", err)
+
+	if err := repo.CommitPreview(ctx, userID, preview.Token, got.Schedules); err != nil {
+		t.Fatalf("CommitPreview: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, userID, item.ID); err != nil {
+		t.Errorf("committed schedule: %v", err)
+	}
+	expectNoRows(t, "CommitPreview twice", repo.CommitPreview(ctx, userID, preview.Token, nil))
+	_, err = repo.GetPreview(ctx, userID, preview.Token)
+	expectNoRows(t, "GetPreview after committing", err)
+
+	expired := domain.SchedulePreview{Token: uuid.NewString(), UserID: userID, ExpiresAt: now.Add(-time.Minute)}
+	if err := repo.SavePreview(ctx, expired); err != nil {
+		t.Fatalf("SavePreview: %v", err)
+	}
+	_, err = repo.GetPreview(ctx, userID, expired.Token)
+	expectNoRows(t, "GetPreview of an expired preview", err)
+	expectNoRows(t, "CommitPreview of an expired preview", repo.CommitPreview(ctx, userID, expired.Token, nil))
+	if n, err := repo.DeleteExpiredPreviews(ctx); err != nil || n < 1 {
+		t.Errorf("DeleteExpiredPreviews: got %d, %v", n, err)
+	}
+}
+
+func testReplans(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	now := time.Now()
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	proposal := domain.ReplanProposal{
+		Token:       uuid.NewString(),
+		UserID:      userID,
+		Day:         time.Date(2025, 8, 5, 0, 0, 0, 0, jakarta),
+		Instruction: "sleep in",
+		Provider:    "openai",
+		Diff: domain.ReplanDiff{
+			Additions: []domain.Schedule{schedule(userID, "Nap", 5)},
+		},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	if err := repo.SaveReplan(ctx, proposal); err != nil {
+		t.Fatalf("SaveReplan: %v", err)
+	}
+	got, err := repo.GetReplan(ctx, userID, proposal.Token, jakarta)
+	if err != nil {
+		t.Fatalf("GetReplan: %v", err)
+	}
+	if !got.Day.Equal(proposal.Day) || got.Instruction != "sleep in" || got.Provider != "openai" ||
+		len(got.Diff.Additions) != 1 || got.Diff.Additions[0].Title != "Nap" {
+		t.Errorf("GetReplan returned %+v", got)
+	}
+	_, err = repo.GetReplan(ctx, newUser(t, repo), proposal.Token, jakarta)
+	expectNoRows(t, "GetReplan of another user", err)
+
+	if err := repo.DeleteReplan(ctx, userID, proposal.Token); err != nil {
+		t.Fatalf("DeleteReplan: %v", err)
+	}
+	expectNoRows(t, "DeleteReplan twice", repo.DeleteReplan(ctx, userID, proposal.Token))
+
+	expired := proposal
+	expired.Token = uuid.NewString()
+	expired.ExpiresAt = now.Add(-time.Minute)
+	if err := repo.SaveReplan(ctx, expired); err != nil {
+		t.Fatalf("SaveReplan: %v", err)
+	}
+	_, err = repo.GetReplan(ctx, userID, expired.Token, jakarta)
+	expectNoRows(t, "GetReplan of an expired replan", err)
+	if n, err := repo.DeleteExpiredReplans(ctx); err != nil || n < 1 {
+		t.Errorf("DeleteExpiredReplans: got %d, %v", n, err)
+	}
+}
+
+func testAcceptReplan(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+	gym := schedule(userID, "Gym", 0)
+	daily := series(userID, "Daily", 2)
+	save(t, repo, gym, daily)
+
+	proposal := domain.ReplanProposal{
+		Token:     uuid.NewString(),
+		UserID:    userID,
+		Day:       base,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := repo.SaveReplan(ctx, proposal); err != nil {
+		t.Fatalf("SaveReplan: %v", err)
+	}
+
+	nap := schedule(userID, "Nap", 5)
+	second := base.Add(2*time.Hour).AddDate(0, 0, 1)
+	cancel := domain.ScheduleException{ScheduleID: daily.ID, OccurrenceStart: second, Cancelled: true}
+
+	// A change that fails halfway saves nothing and keeps the replan
+	missing := schedule(userID, "Missing", 8)
+	failing := domain.SeriesChange{
+		Insert:     []domain.Schedule{nap},
+		Update:     []domain.Schedule{missing},
+		Exceptions: []domain.ScheduleException{cancel},
+	}
+	if err := repo.AcceptReplan(ctx, userID, proposal.Token, failing); err == nil {
+		t.Fatal("AcceptReplan with a missing schedule succeeded")
+	}
+	_, err := repo.GetByID(ctx, userID, nap.ID)
+	expectNoRows(t, "GetByID of an addition after a failed accept", err)
+	_, err = repo.GetException(ctx, daily.ID, second)
+	expectNoRows(t, "GetException after a failed accept", err)
+	if _, err := repo.GetReplan(ctx, userID, proposal.Token, time.UTC); err != nil {
+		t.Fatalf("GetReplan after a failed accept: %v", err)
+	}
+
+	moved := gym
+	moved.StartTime, moved.EndTime = gym.StartTime.Add(time.Hour), gym.EndTime.Add(time.Hour)
+	change := domain.SeriesChange{
+		Insert:     []domain.Schedule{nap},
+		Update:     []domain.Schedule{moved},
+		Exceptions: []domain.ScheduleException{cancel},
+	}
+	expectNoRows(t, "AcceptReplan of another user", repo.AcceptReplan(ctx, newUser(t, repo), proposal.Token, change))
+	if err := repo.AcceptReplan(ctx, userID, proposal.Token, change); err != nil {
+		t.Fatalf("AcceptReplan: %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, userID, gym.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !got.StartTime.Equal(moved.StartTime) || !got.EndTime.Equal(moved.EndTime) {
+		t.Errorf("moved schedule is at %v - %v, want %v - %v", got.StartTime, got.EndTime, moved.StartTime, moved.EndTime)
+	}
+	if _, err := repo.GetByID(ctx, userID, nap.ID); err != nil {
+		t.Errorf("GetByID of the addition: %v", err)
+	}
+	exc, err := repo.GetException(ctx, daily.ID, second)
+	if err != nil || !exc.Cancelled {
+		t.Errorf("GetException: got %+v, %v, want a cancelled occurrence", exc, err)
+	}
+	_, err = repo.GetReplan(ctx, userID, proposal.Token, time.UTC)
+	expectNoRows(t, "GetReplan after accepting", err)
+	expectNoRows(t, "AcceptReplan twice", repo.AcceptReplan(ctx, userID, proposal.Token, domain.SeriesChange{}))
+}
+
+func testProfiles(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+
+	_, err := repo.GetProfile(ctx, userID)
+	expectNoRows(t, "GetProfile before saving", err)
+
+	profile := domain.DefaultUserProfile()
+	profile.UserID = userID
+	profile.Occupation = "blacksmith"
+	profile.UpdatedAt = base
+	if err := repo.SaveProfile(ctx, profile); err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+	got, err := repo.GetProfile(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+	if got.Occupation != "blacksmith" || len(got.Habits) != len(profile.Habits) || !got.UpdatedAt.Equal(base) {
+		t.Errorf("GetProfile returned %+v", got)
+	}
+
+	profile.Occupation = "swordsman"
+	profile.Habits = nil
+	if err := repo.SaveProfile(ctx, profile); err != nil {
+		t.Fatalf("SaveProfile again: %v", err)
+	}
+	got, _ = repo.GetProfile(ctx, userID)
+	if got.Occupation != "swordsman" || len(got.Habits) != 0 {
+		t.Errorf("SaveProfile did not replace the profile: %+v", got)
+	}
+}
+
+func testUsers(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+
+	user, err := repo.GetUserByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if user.Name != "Tester" || user.Email != userID+"@example.com" {
+		t.Errorf("GetUserByID returned %+v", user)
+	}
+	_, err = repo.GetUserByID(ctx, uuid.NewString())
+	expectNoRows(t, "GetUserByID of a missing user", err)
+
+	dup := domain.User{ID: uuid.NewString(), Name: "Copy", Email: user.Email, CreatedAt: base}
+	if err := repo.CreateUser(ctx, dup); !errors.Is(err, domain.ErrEmailTaken) {
+		t.Errorf("CreateUser with a taken email: got %v, want domain.ErrEmailTaken", err)
+	}
+}
+
+func testAPIKeys(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	userID := newUser(t, repo)
+
+	older := domain.APIKey{ID: uuid.NewString(), UserID: userID, Name: "laptop", Prefix: "mh_a", KeyHash: uuid.NewString(), CreatedAt: base}
+	newer := domain.APIKey{ID: uuid.NewString(), UserID: userID, Name: "phone", Prefix: "mh_b", KeyHash: uuid.NewString(), CreatedAt: base.Add(time.Hour)}
+	for _, key := range []domain.APIKey{older, newer} {
+		if err := repo.CreateAPIKey(ctx, key); err != nil {
+			t.Fatalf("CreateAPIKey: %v", err)
+		}
+	}
+
+	got, err := repo.GetAPIKeyByHash(ctx, older.KeyHash)
+	if err != nil || got.ID != older.ID {
+		t.Fatalf("GetAPIKeyByHash: got %+v, %v", got, err)
+	}
+	_, err = repo.GetAPIKeyByHash(ctx, uuid.NewString())
+	expectNoRows(t, "GetAPIKeyByHash of an unknown hash", err)
+	got, err = repo.GetAPIKeyByID(ctx, newer.ID)
+	if err != nil || got.Name != "phone" {
+		t.Fatalf("GetAPIKeyByID: got %+v, %v", got, err)
+	}
+
+	keys, err := repo.ListAPIKeys(ctx, userID)
+	if err != nil {
+		t.Fatalf("ListAPIKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != newer.ID || keys[1].ID != older.ID {
+		t.Errorf("ListAPIKeys should list the newest key first")
+	}
+
+	used := base.Add(2 * time.Hour)
+	if err := repo.TouchAPIKey(ctx, older.ID, used); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+	got, _ = repo.GetAPIKeyByID(ctx, older.ID)
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(used) {
+		t.Errorf("TouchAPIKey not saved: %+v", got)
+	}
+
+	expectNoRows(t, "RevokeAPIKey of another user's key", repo.RevokeAPIKey(ctx, newUser(t, repo), older.ID, used))
+	if err := repo.RevokeAPIKey(ctx, userID, older.ID, used); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	expectNoRows(t, "RevokeAPIKey twice", repo.RevokeAPIKey(ctx, userID, older.ID, used))
+	got, _ = repo.GetAPIKeyByID(ctx, older.ID)
+	if got.RevokedAt == nil || !got.RevokedAt.Equal(used) {
+		t.Errorf("RevokeAPIKey not saved: %+v", got)
+	}
+}
+
+func testJobs(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	job := "repotest-" + uuid.NewString()
+
+	ran, err := repo.WithJobLock(ctx, job, func(ctx context.Context) error {
+		nested, err := repo.WithJobLock(ctx, job, func(context.Context) error {
+			t.Error("WithJobLock ran a job that was already running")
+			return nil
+		})
+		if nested || err != nil {
+			t.Errorf("nested WithJobLock: got %v, %v", nested, err)
+		}
+		return nil
+	})
+	if !ran || err != nil {
+		t.Errorf("WithJobLock: got %v, %v", ran, err)
+	}
+	ran, _ = repo.WithJobLock(ctx, job, func(context.Context) error { return nil })
+	if !ran {
+		t.Error("WithJobLock did not run after the lock was released")
+	}
+}
@@ -0,0 +1,842 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// SQLiteRepo stores everything in a single SQLite file, for development and
+// single-user installs that run without a Postgres server. It needs a cgo
+// build.
+type SQLiteRepo struct {
+	db   *sqlx.DB
+	jobs jobLocks
+}
+
+// NewSQLiteRepo opens or creates the database file at path and makes sure
+// its tables exist
+func NewSQLiteRepo(path string) (*SQLiteRepo, error) {
+	db, err := sqlx.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DB: %w", err)
+	}
+	// SQLite allows a single writer; one connection avoids "database is
+	// locked" errors and keeps an in-memory database alive
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &SQLiteRepo{db: db}, nil
+}
+
+// utcArgs converts time arguments to UTC. The driver writes times as text
+// in their own zone, so stored times only compare correctly when they are
+// all written in the same one.
+func utcArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				t := v.UTC()
+				args[i] = &t
+			}
+		}
+	}
+	return args
+}
+
+func sqliteExec(ctx context.Context, db sqlx.ExecerContext, query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func sqliteGet(ctx context.Context, db sqlx.QueryerContext, dest interface{}, query string, args ...interface{}) error {
+	return sqlx.GetContext(ctx, db, dest, query, utcArgs(args)...)
+}
+
+func sqliteSelect(ctx context.Context, db sqlx.QueryerContext, dest interface{}, query string, args ...interface{}) error {
+	return sqlx.SelectContext(ctx, db, dest, query, utcArgs(args)...)
+}
+
+// placeholders returns "?, ?, ..." with n parameters
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// stringArgs turns ids into query arguments
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+// SaveMany inserts multiple schedules in one batch inside a transaction
+func (r *SQLiteRepo) SaveMany(ctx context.Context, schedules []domain.Schedule) error {
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+
+	if err := sqliteInsertSchedules(ctx, tx, schedules); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// sqliteInsertSchedules batch inserts schedules using the given transaction
+func sqliteInsertSchedules(ctx context.Context, tx *sqlx.Tx, schedules []domain.Schedule) error {
+	query := `INSERT INTO schedules
+		(id, user_id, title, description, start_time, end_time, is_done, repeat_type, repeat_until, rrule, series_id, timezone) VALUES `
+
+	args := []interface{}{}
+	rows := []string{}
+
+	for _, s := range schedules {
+		seriesID := s.SeriesID
+		if seriesID == "" {
+			seriesID = s.ID
+		}
+		timezone := s.Timezone
+		if timezone == "" {
+			timezone = "UTC"
+		}
+
+		rows = append(rows, "("+placeholders(12)+")")
+		args = append(args,
+			s.ID, s.UserID, s.Title, s.Description, s.StartTime, s.EndTime, s.IsDone, s.RepeatType, s.RepeatUntil, s.RRule, seriesID, timezone)
+	}
+
+	query += strings.Join(rows, ",")
+	if _, err := sqliteExec(ctx, tx, query, args...); err != nil {
+		return fmt.Errorf("batch insert failed: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) Update(ctx context.Context, userID, id string, updated domain.Schedule) error {
+	return sqliteUpdateSchedule(ctx, r.db, userID, id, updated)
+}
+
+func sqliteUpdateSchedule(ctx context.Context, db sqlx.ExecerContext, userID, id string, updated domain.Schedule) error {
+	query := `
+		UPDATE schedules
+		SET title = ?, description = ?, start_time = ?, end_time = ?, is_done = ?,
+			repeat_type = ?, repeat_until = ?, rrule = ?
+		WHERE id = ? AND user_id = ?`
+
+	res, err := sqliteExec(ctx, db, query,
+		updated.Title, updated.Description, updated.StartTime, updated.EndTime,
+		updated.IsDone, updated.RepeatType, updated.RepeatUntil, updated.RRule, id, userID)
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetAll(ctx context.Context, userID string, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error) {
+	var schedules []domain.Schedule
+	conditions, args := sqliteScheduleConditions(userID, filter)
+
+	query := `SELECT * FROM schedules WHERE ` + strings.Join(conditions, " AND ")
+
+	// Sorting whitelist
+	allowedSortColumns := map[string]bool{
+		"start_time": true,
+		"end_time":   true,
+		"created_at": true,
+		"title":      true,
+	}
+	sortBy := "start_time" // default
+	if allowedSortColumns[filter.SortBy] {
+		sortBy = filter.SortBy
+	}
+	sortOrder := "ASC"
+	if strings.ToLower(filter.SortOrder) == "desc" {
+		sortOrder = "DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
+
+	// Pagination
+	offset := (page - 1) * limit
+	query += " LIMIT ? OFFSET ?"
+
+	if err := sqliteSelect(ctx, r.db, &schedules, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch schedules: %w", err)
+	}
+
+	// Count query
+	countQuery := `SELECT COUNT(*) FROM schedules WHERE ` + strings.Join(conditions, " AND ")
+	var total int
+	if err := sqliteGet(ctx, r.db, &total, countQuery, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count schedules: %w", err)
+	}
+
+	return schedules, total, nil
+}
+
+// sqliteScheduleConditions builds the WHERE conditions shared by schedule
+// listings. LIKE ignores case for ASCII letters, like ILIKE does in Postgres.
+func sqliteScheduleConditions(userID string, filter dto.ScheduleFilter) ([]string, []interface{}) {
+	args := []interface{}{userID}
+	conditions := []string{"user_id = ?"}
+
+	if filter.IsDone != nil {
+		args = append(args, *filter.IsDone)
+		conditions = append(conditions, "is_done = ?")
+	}
+	if filter.RepeatType != "" {
+		args = append(args, filter.RepeatType)
+		conditions = append(conditions, "repeat_type = ?")
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
+		conditions = append(conditions, "(title LIKE ? OR description LIKE ?)")
+	}
+	if filter.StartAfter != nil {
+		args = append(args, *filter.StartAfter)
+		conditions = append(conditions, "start_time >= ?")
+	}
+	if filter.StartBefore != nil {
+		args = append(args, *filter.StartBefore)
+		conditions = append(conditions, "start_time < ?")
+	}
+	return conditions, args
+}
+
+// GetOneOffs returns every non-recurring schedule matching filter, unpaginated
+func (r *SQLiteRepo) GetOneOffs(ctx context.Context, userID string, filter dto.ScheduleFilter) ([]domain.Schedule, error) {
+	conditions, args := sqliteScheduleConditions(userID, filter)
+	conditions = append(conditions, "rrule = ''")
+
+	var schedules []domain.Schedule
+	query := `SELECT * FROM schedules WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY start_time ASC`
+	if err := sqliteSelect(ctx, r.db, &schedules, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch one-off schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetSeriesInRange returns the recurring series of the user that can have
+// occurrences starting in [from, to)
+func (r *SQLiteRepo) GetSeriesInRange(ctx context.Context, userID string, from, to time.Time) ([]domain.Schedule, error) {
+	var schedules []domain.Schedule
+	err := sqliteSelect(ctx, r.db, &schedules, `
+		SELECT * FROM schedules
+		WHERE user_id = ? AND rrule <> ''
+		AND start_time < ?
+		AND (repeat_until IS NULL OR repeat_until >= ?)
+		ORDER BY start_time ASC`, userID, to, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recurring schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetExceptions returns the exceptions of the given series whose original or
+// overridden start falls in [from, to)
+func (r *SQLiteRepo) GetExceptions(ctx context.Context, scheduleIDs []string, from, to time.Time) ([]domain.ScheduleException, error) {
+	var exceptions []domain.ScheduleException
+	if len(scheduleIDs) == 0 {
+		return exceptions, nil
+	}
+	args := append(stringArgs(scheduleIDs), from, to, from, to)
+	err := sqliteSelect(ctx, r.db, &exceptions, `
+		SELECT * FROM schedule_exceptions
+		WHERE schedule_id IN (`+placeholders(len(scheduleIDs))+`)
+		AND ((occurrence_start >= ? AND occurrence_start < ?)
+			OR (start_time >= ? AND start_time < ?))`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedule exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+// GetException returns the exception of one occurrence, or sql.ErrNoRows
+func (r *SQLiteRepo) GetException(ctx context.Context, scheduleID string, occurrenceStart time.Time) (*domain.ScheduleException, error) {
+	var exc domain.ScheduleException
+	err := sqliteGet(ctx, r.db, &exc, `
+		SELECT * FROM schedule_exceptions
+		WHERE schedule_id = ? AND occurrence_start = ?`, scheduleID, occurrenceStart)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get schedule exception failed: %w", err)
+	}
+	return &exc, nil
+}
+
+func (r *SQLiteRepo) GetByID(ctx context.Context, userID, id string) (*domain.Schedule, error) {
+	var schedule domain.Schedule
+	err := sqliteGet(ctx, r.db, &schedule, `SELECT * FROM schedules WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get by id failed: %w", err)
+	}
+	return &schedule, nil
+}
+
+func (r *SQLiteRepo) DeleteByID(ctx context.Context, userID, id string) error {
+	res, err := sqliteExec(ctx, r.db, `DELETE FROM schedules WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete by id failed: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteAll removes every schedule owned by the user
+func (r *SQLiteRepo) DeleteAll(ctx context.Context, userID string) error {
+	_, err := sqliteExec(ctx, r.db, `DELETE FROM schedules WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("delete all failed: %w", err)
+	}
+	return nil
+}
+
+// SaveException creates or replaces the exception of one occurrence
+func (r *SQLiteRepo) SaveException(ctx context.Context, exc domain.ScheduleException) error {
+	return r.SaveExceptions(ctx, []domain.ScheduleException{exc})
+}
+
+// SaveExceptions creates or replaces several exceptions in one transaction
+func (r *SQLiteRepo) SaveExceptions(ctx context.Context, exceptions []domain.ScheduleException) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := sqliteSaveExceptions(ctx, tx, exceptions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func sqliteSaveExceptions(ctx context.Context, tx *sqlx.Tx, exceptions []domain.ScheduleException) error {
+	for _, exc := range exceptions {
+		_, err := sqliteExec(ctx, tx, `
+			INSERT INTO schedule_exceptions
+				(schedule_id, occurrence_start, title, description, start_time, end_time, is_done, cancelled, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (schedule_id, occurrence_start) DO UPDATE SET
+				title = excluded.title,
+				description = excluded.description,
+				start_time = excluded.start_time,
+				end_time = excluded.end_time,
+				is_done = excluded.is_done,
+				cancelled = excluded.cancelled,
+				updated_at = excluded.updated_at`,
+			exc.ScheduleID, exc.OccurrenceStart, exc.Title, exc.Description,
+			exc.StartTime, exc.EndTime, exc.IsDone, exc.Cancelled, time.Now())
+		if err != nil {
+			return fmt.Errorf("save schedule exception failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetSeriesRows returns every row of a series, oldest first, or
+// sql.ErrNoRows when the series does not exist
+func (r *SQLiteRepo) GetSeriesRows(ctx context.Context, userID, seriesID string) ([]domain.Schedule, error) {
+	var schedules []domain.Schedule
+	err := sqliteSelect(ctx, r.db, &schedules, `
+		SELECT * FROM schedules
+		WHERE user_id = ? AND series_id = ?
+		ORDER BY start_time ASC`, userID, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch series: %w", err)
+	}
+	if len(schedules) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return schedules, nil
+}
+
+// ApplySeriesChange saves an edit spanning several rows of a series in one
+// transaction. Exceptions are moved before rows are deleted so that moving
+// them off a deleted row keeps them.
+func (r *SQLiteRepo) ApplySeriesChange(ctx context.Context, userID string, change domain.SeriesChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := sqliteApplySeriesChange(ctx, tx, userID, change); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func sqliteApplySeriesChange(ctx context.Context, tx *sqlx.Tx, userID string, change domain.SeriesChange) error {
+	if len(change.Insert) > 0 {
+		if err := sqliteInsertSchedules(ctx, tx, change.Insert); err != nil {
+			return err
+		}
+	}
+	for _, s := range change.Update {
+		if err := sqliteUpdateSchedule(ctx, tx, userID, s.ID, s); err != nil {
+			return err
+		}
+	}
+	if err := sqliteSaveExceptions(ctx, tx, change.Exceptions); err != nil {
+		return err
+	}
+	for _, m := range change.Move {
+		if err := sqliteMoveExceptions(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+	if len(change.Delete) > 0 {
+		_, err := sqliteExec(ctx, tx, `DELETE FROM schedules WHERE user_id = ? AND id IN (`+placeholders(len(change.Delete))+`)`,
+			append([]interface{}{userID}, stringArgs(change.Delete)...)...)
+		if err != nil {
+			return fmt.Errorf("delete series rows failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// sqliteMoveExceptions rekeys exceptions one by one, since SQLite cannot
+// shift the stored times itself. They are visited in the direction of the
+// shift so that no exception is moved onto one that has not moved yet.
+func sqliteMoveExceptions(ctx context.Context, tx *sqlx.Tx, m domain.ExceptionMove) error {
+	var exceptions []domain.ScheduleException
+	err := sqliteSelect(ctx, tx, &exceptions, `
+		SELECT * FROM schedule_exceptions
+		WHERE schedule_id = ? AND occurrence_start >= ?`, m.FromID, m.Since)
+	if err != nil {
+		return fmt.Errorf("move schedule exceptions failed: %w", err)
+	}
+	sort.Slice(exceptions, func(i, j int) bool {
+		if m.Shift > 0 {
+			return exceptions[i].OccurrenceStart.After(exceptions[j].OccurrenceStart)
+		}
+		return exceptions[i].OccurrenceStart.Before(exceptions[j].OccurrenceStart)
+	})
+
+	for _, exc := range exceptions {
+		from := exc.OccurrenceStart
+		exc.Shift(m.Shift)
+		_, err := sqliteExec(ctx, tx, `
+			UPDATE schedule_exceptions
+			SET schedule_id = ?, occurrence_start = ?, start_time = ?, end_time = ?
+			WHERE schedule_id = ? AND occurrence_start = ?`,
+			m.ToID, exc.OccurrenceStart, exc.StartTime, exc.EndTime, m.FromID, from)
+		if err != nil {
+			return fmt.Errorf("move schedule exceptions failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) SavePreview(ctx context.Context, preview domain.SchedulePreview) error {
+	items, err := json.Marshal(preview.Schedules)
+	if err != nil {
+		return fmt.Errorf("encode preview items failed: %w", err)
+	}
+
+	_, err = sqliteExec(ctx, r.db, `
+		INSERT INTO schedule_previews (token, user_id, provider, items, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		preview.Token, preview.UserID, preview.Provider, string(items), time.Now(), preview.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("save preview failed: %w", err)
+	}
+	return nil
+}
+
+// GetPreview returns a preview that has not expired yet, or sql.ErrNoRows
+func (r *SQLiteRepo) GetPreview(ctx context.Context, userID, token string) (*domain.SchedulePreview, error) {
+	var row struct {
+		Token     string    `db:"token"`
+		UserID    string    `db:"user_id"`
+		Provider  string    `db:"provider"`
+		Items     []byte    `db:"items"`
+		CreatedAt time.Time `db:"created_at"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+	err := sqliteGet(ctx, r.db, &row, `
+		SELECT token, user_id, provider, items, created_at, expires_at FROM schedule_previews
+		WHERE token = ? AND user_id = ? AND expires_at > ?`, token, userID, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get preview failed: %w", err)
+	}
+
+	preview := &domain.SchedulePreview{
+		Token:     row.Token,
+		UserID:    row.UserID,
+		Provider:  row.Provider,
+		CreatedAt: row.CreatedAt,
+		ExpiresAt: row.ExpiresAt,
+	}
+	if err := json.Unmarshal(row.Items, &preview.Schedules); err != nil {
+		return nil, fmt.Errorf("decode preview items failed: %w", err)
+	}
+	return preview, nil
+}
+
+// CommitPreview consumes the preview and saves the schedules in one
+// transaction, so a token can only ever be committed once.
+func (r *SQLiteRepo) CommitPreview(ctx context.Context, userID, token string, schedules []domain.Schedule) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := sqliteExec(ctx, tx, `
+		DELETE FROM schedule_previews
+		WHERE token = ? AND user_id = ? AND expires_at > ?`, token, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("consume preview failed: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if len(schedules) > 0 {
+		if err := sqliteInsertSchedules(ctx, tx, schedules); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteExpiredPreviews(ctx context.Context) (int64, error) {
+	res, err := sqliteExec(ctx, r.db, `DELETE FROM schedule_previews WHERE expires_at <= ?`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired previews failed: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	return rows, nil
+}
+
+func (r *SQLiteRepo) SaveReplan(ctx context.Context, proposal domain.ReplanProposal) error {
+	diff, err := json.Marshal(proposal.Diff)
+	if err != nil {
+		return fmt.Errorf("encode replan diff failed: %w", err)
+	}
+
+	_, err = sqliteExec(ctx, r.db, `
+		INSERT INTO schedule_replans (token, user_id, day, instruction, provider, diff, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		proposal.Token, proposal.UserID, proposal.Day.Format("2006-01-02"), proposal.Instruction,
+		proposal.Provider, string(diff), time.Now(), proposal.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("save replan failed: %w", err)
+	}
+	return nil
+}
+
+// GetReplan returns a replan that has not expired yet, or sql.ErrNoRows.
+// Day is midnight of the replanned date in loc.
+func (r *SQLiteRepo) GetReplan(ctx context.Context, userID, token string, loc *time.Location) (*domain.ReplanProposal, error) {
+	var row struct {
+		Token       string    `db:"token"`
+		UserID      string    `db:"user_id"`
+		Day         string    `db:"day"`
+		Instruction string    `db:"instruction"`
+		Provider    string    `db:"provider"`
+		Diff        []byte    `db:"diff"`
+		CreatedAt   time.Time `db:"created_at"`
+		ExpiresAt   time.Time `db:"expires_at"`
+	}
+	err := sqliteGet(ctx, r.db, &row, `
+		SELECT token, user_id, day, instruction, provider, diff, created_at, expires_at
+		FROM schedule_replans
+		WHERE token = ? AND user_id = ? AND expires_at > ?`, token, userID, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get replan failed: %w", err)
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", row.Day, loc)
+	if err != nil {
+		return nil, fmt.Errorf("decode replan day failed: %w", err)
+	}
+	proposal := &domain.ReplanProposal{
+		Token:       row.Token,
+		UserID:      row.UserID,
+		Day:         day,
+		Instruction: row.Instruction,
+		Provider:    row.Provider,
+		CreatedAt:   row.CreatedAt,
+		ExpiresAt:   row.ExpiresAt,
+	}
+	if err := json.Unmarshal(row.Diff, &proposal.Diff); err != nil {
+		return nil, fmt.Errorf("decode replan diff failed: %w", err)
+	}
+	return proposal, nil
+}
+
+// DeleteReplan consumes a replan that has not expired yet, returning
+// sql.ErrNoRows when there is none, so a replan is only ever applied once
+func (r *SQLiteRepo) DeleteReplan(ctx context.Context, userID, token string) error {
+	res, err := sqliteExec(ctx, r.db, `
+		DELETE FROM schedule_replans
+		WHERE token = ? AND user_id = ? AND expires_at > ?`, token, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("delete replan failed: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptReplan consumes a replan and saves the change it resolved to in one
+// transaction, so a failed change leaves the replan to be retried.
+func (r *SQLiteRepo) AcceptReplan(ctx context.Context, userID, token string, change domain.SeriesChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := sqliteExec(ctx, tx, `
+		DELETE FROM schedule_replans
+		WHERE token = ? AND user_id = ? AND expires_at > ?`, token, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("consume replan failed: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := sqliteApplySeriesChange(ctx, tx, userID, change); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteExpiredReplans(ctx context.Context) (int64, error) {
+	res, err := sqliteExec(ctx, r.db, `DELETE FROM schedule_replans WHERE expires_at <= ?`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired replans failed: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	return rows, nil
+}
+
+// GetProfile returns the stored profile, or sql.ErrNoRows if none was saved
+func (r *SQLiteRepo) GetProfile(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	var row profileRow
+	if err := sqliteGet(ctx, r.db, &row, `SELECT * FROM user_profiles WHERE user_id = ?`, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get profile failed: %w", err)
+	}
+
+	profile := &domain.UserProfile{
+		UserID:     row.UserID,
+		Occupation: row.Occupation,
+		WakeTime:   row.WakeTime,
+		SleepTime:  row.SleepTime,
+		Timezone:   row.Timezone,
+		WorkStart:  row.WorkStart,
+		WorkEnd:    row.WorkEnd,
+		UpdatedAt:  row.UpdatedAt,
+	}
+	if err := json.Unmarshal(row.Habits, &profile.Habits); err != nil {
+		return nil, fmt.Errorf("decode habits failed: %w", err)
+	}
+	if err := json.Unmarshal(row.BlockedTimes, &profile.BlockedTimes); err != nil {
+		return nil, fmt.Errorf("decode blocked times failed: %w", err)
+	}
+	return profile, nil
+}
+
+// SaveProfile creates or replaces the profile
+func (r *SQLiteRepo) SaveProfile(ctx context.Context, profile domain.UserProfile) error {
+	habits, err := json.Marshal(profile.Habits)
+	if err != nil {
+		return fmt.Errorf("encode habits failed: %w", err)
+	}
+	blocked, err := json.Marshal(profile.BlockedTimes)
+	if err != nil {
+		return fmt.Errorf("encode blocked times failed: %w", err)
+	}
+
+	_, err = sqliteExec(ctx, r.db, `
+		INSERT INTO user_profiles
+			(user_id, occupation, wake_time, sleep_time, timezone, work_start, work_end, habits, blocked_times, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			occupation = excluded.occupation,
+			wake_time = excluded.wake_time,
+			sleep_time = excluded.sleep_time,
+			timezone = excluded.timezone,
+			work_start = excluded.work_start,
+			work_end = excluded.work_end,
+			habits = excluded.habits,
+			blocked_times = excluded.blocked_times,
+			updated_at = excluded.updated_at`,
+		profile.UserID, profile.Occupation, profile.WakeTime, profile.SleepTime, profile.Timezone,
+		profile.WorkStart, profile.WorkEnd, string(habits), string(blocked), profile.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save profile failed: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) CreateUser(ctx context.Context, user domain.User) error {
+	_, err := sqliteExec(ctx, r.db, `
+		INSERT INTO users (id, name, email, created_at) VALUES (?, ?, ?, ?)`,
+		user.ID, user.Name, user.Email, user.CreatedAt)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return domain.ErrEmailTaken
+		}
+		return fmt.Errorf("create user failed: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	var user domain.User
+	err := sqliteGet(ctx, r.db, &user, `SELECT id, name, email, created_at FROM users WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get user failed: %w", err)
+	}
+	return &user, nil
+}
+
+func (r *SQLiteRepo) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	_, err := sqliteExec(ctx, r.db, `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create api key failed: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := sqliteGet(ctx, r.db, &key, `SELECT * FROM api_keys WHERE key_hash = ?`, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get api key failed: %w", err)
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByID is not scoped to a user so callers can tell "not found"
+// apart from "owned by someone else"
+func (r *SQLiteRepo) GetAPIKeyByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := sqliteGet(ctx, r.db, &key, `SELECT * FROM api_keys WHERE id = ?`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get api key failed: %w", err)
+	}
+	return &key, nil
+}
+
+func (r *SQLiteRepo) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := sqliteSelect(ctx, r.db, &keys, `
+		SELECT * FROM api_keys WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys failed: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *SQLiteRepo) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error {
+	res, err := sqliteExec(ctx, r.db, `
+		UPDATE api_keys SET revoked_at = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL`, at, id, userID)
+	if err != nil {
+		return fmt.Errorf("revoke api key failed: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	_, err := sqliteExec(ctx, r.db, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, at, id)
+	if err != nil {
+		return fmt.Errorf("touch api key failed: %w", err)
+	}
+	return nil
+}
+
+// WithJobLock runs fn unless the job is already running. An SQLite file is
+// served by a single process, so a lock in memory is enough.
+func (r *SQLiteRepo) WithJobLock(ctx context.Context, job string, fn func(context.Context) error) (bool, error) {
+	return r.jobs.run(ctx, job, fn)
+}
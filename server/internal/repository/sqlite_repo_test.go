@@ -0,0 +1,18 @@
+package repository_test
+
+import (
+	"testing"
+
+	"murim-helper/internal/repository"
+	"murim-helper/internal/repository/repotest"
+)
+
+func TestSQLiteRepo(t *testing.T) {
+	repotest.TestRepository(t, func(t *testing.T) repository.Repository {
+		repo, err := repository.NewSQLiteRepo(t.TempDir() + "/db")
+		if err != nil {
+			t.Fatalf("NewSQLiteRepo: %v", err)
+		}
+		return repo
+	})
+}
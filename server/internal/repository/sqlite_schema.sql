@@ -0,0 +1,100 @@
+-- Schema of the SQLite backend, the same tables the Postgres migrations
+-- end up with. Times are stored as text in UTC, JSON as text.
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO users (id, name, email)
+VALUES ('00000000-0000-0000-0000-000000000000', 'Default user', 'default@murimhelper.local');
+
+CREATE TABLE IF NOT EXISTS schedules (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    series_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    is_done BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    repeat_type TEXT NOT NULL DEFAULT 'none',
+    repeat_until TIMESTAMP,
+    rrule TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT 'UTC'
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_user_start_time ON schedules (user_id, start_time);
+CREATE INDEX IF NOT EXISTS idx_schedules_user_series ON schedules (user_id, series_id);
+
+CREATE TABLE IF NOT EXISTS schedule_exceptions (
+    schedule_id TEXT NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    occurrence_start TIMESTAMP NOT NULL,
+    title TEXT,
+    description TEXT,
+    start_time TIMESTAMP,
+    end_time TIMESTAMP,
+    is_done BOOLEAN NOT NULL DEFAULT FALSE,
+    cancelled BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (schedule_id, occurrence_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_exceptions_start_time ON schedule_exceptions (start_time);
+
+CREATE TABLE IF NOT EXISTS schedule_previews (
+    token TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL DEFAULT '',
+    items TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_previews_expires_at ON schedule_previews (expires_at);
+
+CREATE TABLE IF NOT EXISTS schedule_replans (
+    token TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    instruction TEXT NOT NULL,
+    provider TEXT NOT NULL DEFAULT '',
+    diff TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_replans_expires_at ON schedule_replans (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    occupation TEXT NOT NULL DEFAULT '',
+    wake_time TEXT NOT NULL,
+    sleep_time TEXT NOT NULL,
+    timezone TEXT NOT NULL,
+    work_start TEXT NOT NULL DEFAULT '',
+    work_end TEXT NOT NULL DEFAULT '',
+    habits TEXT NOT NULL DEFAULT '[]',
+    blocked_times TEXT NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+-- Dropped along with the recurrence job; databases created before still
+-- have them
+DROP TABLE IF EXISTS schedule_occurrences;
+DROP TABLE IF EXISTS cron_runs;
@@ -10,18 +10,54 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
-func StartCronJobs(uc usecase.ScheduleUsecase) {
+// JobLocker makes sure only one replica runs a job at a time. ran is false
+// when another replica holds the lock and fn was skipped.
+type JobLocker interface {
+	WithJobLock(ctx context.Context, job string, fn func(context.Context) error) (ran bool, err error)
+}
+
+const (
+	purgePreviewsJob      = "purge_expired_previews"
+	purgePreviewsSchedule = "@hourly"
+)
+
+// Job is a background job and its cron spec
+type Job struct {
+	Name     string
+	Schedule string
+}
+
+// Jobs lists the jobs StartCronJobs schedules
+func Jobs() []Job {
+	return []Job{
+		{Name: purgePreviewsJob, Schedule: purgePreviewsSchedule},
+	}
+}
+
+// StartCronJobs schedules the background jobs. Recurring schedules need no
+// job: their occurrences are expanded from the rule whenever they are read.
+func StartCronJobs(uc usecase.ScheduleUsecase, locker JobLocker) {
 	c := cron.New()
-	// Run every day at midnight
-	c.AddFunc("0 0 * * *", func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		log.Println("[CRON] Processing repeating schedules...")
-		if err := uc.ProcessRepeatingSchedules(ctx); err != nil {
-			log.Printf("[CRON] Error: %v", err)
-		} else {
-			log.Println("[CRON] Repeating schedules processed successfully")
-		}
+
+	// Clean up previews that were never committed
+	c.AddFunc(purgePreviewsSchedule, func() {
+		runLocked(locker, purgePreviewsJob, 30*time.Second, uc.PurgeExpiredPreviews)
 	})
 	c.Start()
 }
+
+// runLocked runs one job under its lock and logs the outcome
+func runLocked(locker JobLocker, job string, timeout time.Duration, fn func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ran, err := locker.WithJobLock(ctx, job, fn)
+	switch {
+	case err != nil:
+		log.Printf("[CRON] %s failed: %v", job, err)
+	case !ran:
+		log.Printf("[CRON] %s skipped, another instance is running it", job)
+	default:
+		log.Printf("[CRON] %s finished successfully", job)
+	}
+}
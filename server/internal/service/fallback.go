@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"murim-helper/internal/domain"
+)
+
+type namedGenerator struct {
+	name      string
+	generator ScheduleGenerator
+}
+
+// GeneratorChain tries each configured provider in order until one returns
+// a non-empty schedule.
+type GeneratorChain struct {
+	generators []namedGenerator
+}
+
+// ChainError is returned when every provider in the chain failed.
+type ChainError struct {
+	Attempts []domain.ProviderAttempt
+}
+
+func (e *ChainError) Error() string {
+	reasons := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		reasons[i] = a.Provider + ": " + a.Error
+	}
+	return "all AI providers failed: " + strings.Join(reasons, "; ")
+}
+
+// NewGeneratorChain builds the providers in the given order. Providers that
+// are not configured (e.g. missing API key) are skipped with a warning; at
+// least one must be available. Any other error, such as an unknown provider
+// name, is returned.
+func NewGeneratorChain(providers ...string) (*GeneratorChain, error) {
+	chain := &GeneratorChain{}
+	seen := map[string]bool{}
+
+	for _, name := range providers {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		gen, err := NewScheduleGenerator(name)
+		if errors.Is(err, ErrProviderNotConfigured) {
+			log.Printf("[AI] skipping provider %s: %v", name, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		chain.generators = append(chain.generators, namedGenerator{name: name, generator: gen})
+	}
+
+	if len(chain.generators) == 0 {
+		return nil, errors.New("no AI provider is configured")
+	}
+	return chain, nil
+}
+
+// Providers returns the names of the usable providers in fallback order.
+func (c *GeneratorChain) Providers() []string {
+	names := make([]string, len(c.generators))
+	for i, g := range c.generators {
+		names[i] = g.name
+	}
+	return names
+}
+
+// Generate runs the chain and reports which provider produced the schedule
+// along with the reason every earlier provider failed.
+func (c *GeneratorChain) Generate(ctx context.Context, description string, profile domain.UserProfile) (*domain.GenerationResult, error) {
+	var schedules []domain.Schedule
+	provider, attempts, err := c.try(ctx, "schedule generation", func(g namedGenerator) error {
+		var err error
+		schedules, err = g.generator.GenerateScheduleFromText(ctx, description, profile)
+		if err == nil && len(schedules) == 0 {
+			err = errors.New("provider returned no schedules")
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.GenerationResult{
+		Schedules: schedules,
+		Provider:  provider,
+		Attempts:  attempts,
+	}, nil
+}
+
+// Replan asks the chain for changes to an existing day, falling back like
+// Generate does.
+func (c *GeneratorChain) Replan(ctx context.Context, req ReplanRequest) (*domain.ReplanDiff, string, []domain.ProviderAttempt, error) {
+	var diff *domain.ReplanDiff
+	provider, attempts, err := c.try(ctx, "replanning", func(g namedGenerator) error {
+		replanner, ok := g.generator.(DayReplanner)
+		if !ok {
+			return errors.New("provider cannot replan a day")
+		}
+		var err error
+		diff, err = replanner.ReplanDay(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return diff, provider, attempts, nil
+}
+
+// QuickAdd asks the chain to read a quick-add note the rule-based parser
+// could not, falling back like Generate does.
+func (c *GeneratorChain) QuickAdd(ctx context.Context, req QuickAddRequest) (*domain.Schedule, string, []domain.ProviderAttempt, error) {
+	var schedule *domain.Schedule
+	provider, attempts, err := c.try(ctx, "quick add", func(g namedGenerator) error {
+		parser, ok := g.generator.(QuickAddParser)
+		if !ok {
+			return errors.New("provider cannot parse quick-add text")
+		}
+		var err error
+		schedule, err = parser.ParseQuickAdd(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return schedule, provider, attempts, nil
+}
+
+// try calls fn with each provider in order until one succeeds, returning
+// its name and why every earlier provider failed. Once ctx is done the
+// chain stops instead of falling back, since later providers would share
+// the same expired deadline.
+func (c *GeneratorChain) try(ctx context.Context, what string, fn func(g namedGenerator) error) (string, []domain.ProviderAttempt, error) {
+	var attempts []domain.ProviderAttempt
+
+	for _, g := range c.generators {
+		if err := ctx.Err(); err != nil {
+			return "", nil, fmt.Errorf("%s aborted after %d attempt(s): %w", what, len(attempts), err)
+		}
+
+		if err := fn(g); err != nil {
+			log.Printf("[AI] provider %s failed: %v", g.name, err)
+			attempts = append(attempts, domain.ProviderAttempt{Provider: g.name, Error: err.Error()})
+			continue
+		}
+		return g.name, attempts, nil
+	}
+
+	return "", nil, &ChainError{Attempts: attempts}
+}
+
+// GenerateScheduleFromText lets the chain be used wherever a single
+// ScheduleGenerator is expected.
+func (c *GeneratorChain) GenerateScheduleFromText(ctx context.Context, description string, profile domain.UserProfile) ([]domain.Schedule, error) {
+	result, err := c.Generate(ctx, description, profile)
+	if err != nil {
+		return nil, err
+	}
+	return result.Schedules, nil
+}
+
+var _ ScheduleGenerator = (*GeneratorChain)(nil)
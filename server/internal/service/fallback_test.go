@@ -0,0 +1,31 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"murim-helper/internal/service"
+)
+
+func TestNewGeneratorChain(t *testing.T) {
+	t.Setenv("GROQ_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	// Providers without credentials are skipped
+	chain, err := service.NewGeneratorChain(service.ProviderGroq, service.ProviderOpenAI, service.ProviderOllama)
+	if err != nil {
+		t.Fatalf("NewGeneratorChain: %v", err)
+	}
+	if got := strings.Join(chain.Providers(), ","); got != service.ProviderOllama {
+		t.Errorf("providers = %q, want %q", got, service.ProviderOllama)
+	}
+
+	// A name that is not registered is a configuration mistake
+	if _, err := service.NewGeneratorChain("groc", service.ProviderOllama); err == nil || !strings.Contains(err.Error(), "unknown AI provider") {
+		t.Errorf("unknown provider: got %v, want an unknown AI provider error", err)
+	}
+
+	if _, err := service.NewGeneratorChain(service.ProviderGroq); err == nil {
+		t.Error("chain without any configured provider succeeded")
+	}
+}
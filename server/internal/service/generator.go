@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"murim-helper/internal/domain"
+)
+
+const (
+	ProviderGroq   = "groq"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// defaultMaxRepairAttempts bounds how often a model is re-prompted with
+// validation errors before the generation is given up.
+const defaultMaxRepairAttempts = 2
+
+// ScheduleGenerator turns a free-text description of a day into schedules
+// shaped by the user's profile. Every AI provider implements it so the
+// usecase never depends on a vendor. Implementations must abort the upstream
+// call once ctx is done.
+type ScheduleGenerator interface {
+	GenerateScheduleFromText(ctx context.Context, description string, profile domain.UserProfile) ([]domain.Schedule, error)
+}
+
+// ErrProviderNotConfigured is wrapped by a ClientFactory whose credentials
+// are missing, so the chain can skip it instead of failing to start.
+var ErrProviderNotConfigured = errors.New("AI provider is not configured")
+
+// ClientFactory builds a provider, returning an error wrapping
+// ErrProviderNotConfigured when it is not configured.
+type ClientFactory func() (LLMClient, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]ClientFactory{
+		ProviderGroq:   NewGroqService,
+		ProviderOpenAI: NewOpenAIService,
+		ProviderOllama: NewOllamaService,
+	}
+)
+
+// RegisterProvider makes a provider available under the given name.
+// Registering an existing name replaces it.
+func RegisterProvider(name string, factory ClientFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(name)] = factory
+}
+
+// Providers lists the registered provider names in alphabetical order.
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLLMClient builds the raw chat client registered under name.
+func NewLLMClient(name string) (LLMClient, error) {
+	registryMu.RLock()
+	factory, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider %q (available: %s)", name, strings.Join(Providers(), ", "))
+	}
+	return factory()
+}
+
+// NewScheduleGenerator builds the provider registered under name (e.g. AI_PROVIDER).
+// AI_MAX_REPAIR_ATTEMPTS overrides how many repair prompts are allowed.
+func NewScheduleGenerator(name string) (ScheduleGenerator, error) {
+	client, err := NewLLMClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRepairs := defaultMaxRepairAttempts
+	if v := os.Getenv("AI_MAX_REPAIR_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid AI_MAX_REPAIR_ATTEMPTS %q", v)
+		}
+		maxRepairs = n
+	}
+
+	return &llmScheduleGenerator{name: name, client: client, maxRepairs: maxRepairs}, nil
+}
+
+// InvalidOutputError is returned when a model keeps producing output that
+// fails validation after all repair attempts.
+type InvalidOutputError struct {
+	Provider string
+	Attempts int
+	Problems []string
+}
+
+func (e *InvalidOutputError) Error() string {
+	return fmt.Sprintf("%s produced invalid schedule after %d attempt(s): %s",
+		e.Provider, e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+type llmScheduleGenerator struct {
+	name       string
+	client     LLMClient
+	maxRepairs int
+}
+
+// GenerateScheduleFromText prompts the model, validates its answer and, when
+// validation fails, sends the problems back so the model can correct itself.
+func (g *llmScheduleGenerator) GenerateScheduleFromText(ctx context.Context, description string, profile domain.UserProfile) ([]domain.Schedule, error) {
+	var schedules []domain.Schedule
+	err := g.completeValid(ctx, buildSchedulePrompt(description, profile, time.Now()), "JSON array", func(reply string) []string {
+		var problems []string
+		schedules, problems = parseAndValidateSchedules(reply)
+		return problems
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// completeValid sends prompt and re-prompts with the problems parse reports
+// until a reply passes or the repair attempts run out. format names what
+// the reply must be, e.g. "JSON array".
+func (g *llmScheduleGenerator) completeValid(ctx context.Context, prompt, format string, parse func(reply string) []string) error {
+	messages := []Message{
+		{Role: RoleUser, Content: prompt},
+	}
+
+	var problems []string
+	for attempt := 0; attempt <= g.maxRepairs; attempt++ {
+		reply, err := g.client.Complete(ctx, messages)
+		if err != nil {
+			return err
+		}
+
+		if problems = parse(reply); len(problems) == 0 {
+			return nil
+		}
+
+		messages = append(messages,
+			Message{Role: RoleAssistant, Content: reply},
+			Message{Role: RoleUser, Content: buildRepairPrompt(problems, format)},
+		)
+	}
+
+	return &InvalidOutputError{Provider: g.name, Attempts: g.maxRepairs + 1, Problems: problems}
+}
+
+// statusError describes a non-2xx response from a provider, keeping the
+// reason (e.g. rate limiting) visible in the fallback attempts.
+func statusError(provider string, status int, body []byte) error {
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 200 {
+		msg = msg[:200]
+	}
+	return fmt.Errorf("%s returned status %d: %s", provider, status, msg)
+}
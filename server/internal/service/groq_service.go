@@ -2,77 +2,53 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"strings"
-	"time"
-
-	"murim-helper/internal/domain"
 )
 
 type groqService struct {
 	ApiKey string
 }
 
-type GroqService interface {
-	GenerateScheduleFromText(description string) ([]domain.Schedule, error)
-}
-
-func NewGroqService() GroqService {
+func NewGroqService() (LLMClient, error) {
 	apiKey := os.Getenv("GROQ_API_KEY")
 	if apiKey == "" {
-		panic("GROQ_API_KEY environment variable is not set")
+		return nil, fmt.Errorf("%w: GROQ_API_KEY environment variable is not set", ErrProviderNotConfigured)
 	}
-	return &groqService{ApiKey: apiKey}
+	return &groqService{ApiKey: apiKey}, nil
 }
 
-func (g *groqService) GenerateScheduleFromText(description string) ([]domain.Schedule, error) {
-	prompt := fmt.Sprintf(`
-	You are a discipline assistant. Based on this input: "%s",
-	generate a full-day schedule in JSON format with title, description, start_time, end_time (in ISO 8601 format like "2025-08-05T07:00:00+07:00").
-	Include morning Bible reading, meals, work, gym, and night prayer — even if the user doesn’t mention them.
-
-	IMPORTANT:
-	- Determine the date based on the user's description (e.g., "tomorrow", "next Monday", or specific date).
-	- ALL tasks, including the default ones, must use the same inferred date.
-	- If no date is mentioned, use today (%s).
-	- Start the day at 07:00 and end at 23:00 in Jakarta timezone (UTC+7).
-
-	Respond ONLY with a valid JSON array like this:
-	[
-		{
-			"title": "Task Title",
-			"description": "What it is",
-			"start_time": "2025-08-05T07:00:00+07:00",
-			"end_time": "2025-08-05T08:00:00+07:00"
-		}
-	]
-	`, description, time.Now().Format("2006-01-02"))
-
+func (g *groqService) Complete(ctx context.Context, messages []Message) (string, error) {
 	reqBody := map[string]interface{}{
-		"model": "llama3-70b-8192",
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"model":       "llama3-70b-8192",
+		"messages":    messages,
 		"temperature": 0.3,
 	}
 
 	jsonData, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("POST", "https://api.groq.com/openai/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.groq.com/openai/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("groq request build failed: %w", err)
+	}
 	req.Header.Set("Authorization", "Bearer "+g.ApiKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 
 	if err != nil {
-		return nil, fmt.Errorf("groq request failed: %w", err)
+		return "", fmt.Errorf("groq request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError("groq", resp.StatusCode, body)
+	}
 
 	var result struct {
 		Choices []struct {
@@ -82,19 +58,12 @@ func (g *groqService) GenerateScheduleFromText(description string) ([]domain.Sch
 		} `json:"choices"`
 	}
 
-	err = json.Unmarshal(body, &result)
-	if err != nil || len(result.Choices) == 0 {
-		return nil, fmt.Errorf("groq decode error: %w", err)
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("groq decode error: %w", err)
 	}
-
-	// Clean & extract only the JSON array
-	content := result.Choices[0].Message.Content
-	start := strings.Index(content, "[")
-	end := strings.LastIndex(content, "]")
-	if start == -1 || end == -1 {
-		return nil, fmt.Errorf("no JSON array found in Groq response")
+	if len(result.Choices) == 0 {
+		return "", errors.New("groq returned no choices")
 	}
 
-	jsonOnly := content[start : end+1]
-	return domain.ParseSchedulesFromJSON(jsonOnly)
+	return result.Choices[0].Message.Content, nil
 }
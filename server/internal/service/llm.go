@@ -0,0 +1,22 @@
+package service
+
+import "context"
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is a single turn of a chat conversation with a provider.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// LLMClient sends a conversation to an AI provider and returns the text of
+// its reply. Providers only implement this; prompting, parsing and
+// validation live in the schedule generator on top of it.
+type LLMClient interface {
+	Complete(ctx context.Context, messages []Message) (string, error)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"murim-helper/internal/domain"
+)
+
+var schedulePromptTemplate = template.Must(template.New("schedule").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`
+You are a discipline assistant. Based on this input: "{{.Description}}",
+generate a full-day schedule in JSON format with title, description, start_time, end_time (in ISO 8601 format like "{{.ExampleStart}}").
+
+About the user:
+{{- if .Profile.Occupation}}
+- Works as a {{.Profile.Occupation}}.
+{{- end}}
+- Wakes up at {{.Profile.WakeTime}} and goes to bed at {{.Profile.SleepTime}}.
+{{- if .Profile.WorkStart}}
+- Works from {{.Profile.WorkStart}} to {{.Profile.WorkEnd}}.
+{{- end}}
+{{- range .Profile.Habits}}
+- Habit: {{.Title}}{{if .Time}} at {{.Time}}{{end}}{{if .DurationMinutes}} for {{.DurationMinutes}} minutes{{end}}{{if .Days}} (only on {{join .Days ", "}}){{end}}.
+{{- end}}
+{{- range .Profile.BlockedTimes}}
+- Unavailable from {{.Start}} to {{.End}}{{if .Title}} ({{.Title}}){{end}}{{if .Days}} on {{join .Days ", "}}{{end}}; schedule nothing then.
+{{- end}}
+Include meals, work, rest and every habit above that applies to the day — even if the user doesn't mention them.
+
+IMPORTANT:
+- Determine the date based on the user's description (e.g., "tomorrow", "next Monday", or specific date).
+- ALL tasks, including the default ones, must use the same inferred date.
+- If no date is mentioned, use today ({{.Today}}, {{.Weekday}}).
+- Start the day at {{.Profile.WakeTime}} and end at {{.Profile.SleepTime}} in the {{.Profile.Timezone}} timezone (UTC{{.Offset}}).
+- Items must be in chronological order and must not overlap.
+- Only when the user asks for something recurring, set "rrule" to an RFC 5545 rule such as "FREQ=WEEKLY;BYDAY=MO,WE,FR" or "FREQ=MONTHLY;BYDAY=-1FR;COUNT=6".
+
+Respond ONLY with a valid JSON array matching this JSON schema:
+{{.Schema}}
+
+Example:
+[
+	{
+		"title": "Task Title",
+		"description": "What it is",
+		"start_time": "{{.ExampleStart}}",
+		"end_time": "{{.ExampleEnd}}"
+	}
+]
+`))
+
+func buildSchedulePrompt(description string, profile domain.UserProfile, now time.Time) string {
+	now = now.In(profile.Location())
+	wake, _ := time.Parse("15:04", profile.WakeTime)
+	exampleStart := time.Date(now.Year(), now.Month(), now.Day(), wake.Hour(), wake.Minute(), 0, 0, now.Location())
+
+	var buf bytes.Buffer
+	err := schedulePromptTemplate.Execute(&buf, map[string]interface{}{
+		"Description":  description,
+		"Profile":      profile,
+		"Today":        now.Format("2006-01-02"),
+		"Weekday":      now.Format("Monday"),
+		"Offset":       exampleStart.Format("-07:00"),
+		"ExampleStart": exampleStart.Format(time.RFC3339),
+		"ExampleEnd":   exampleStart.Add(time.Hour).Format(time.RFC3339),
+		"Schema":       scheduleJSONSchema,
+	})
+	if err != nil {
+		// The template is static, so this only happens on a programming error.
+		panic(fmt.Sprintf("render schedule prompt: %v", err))
+	}
+	return buf.String()
+}
+
+func buildRepairPrompt(problems []string, format string) string {
+	return fmt.Sprintf(`Your previous answer was rejected for these reasons:
+- %s
+
+Fix every problem and respond again with ONLY the corrected %s.`, strings.Join(problems, "\n- "), format)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"murim-helper/internal/domain"
+)
+
+// QuickAddRequest is one line of text to turn into a single schedule
+type QuickAddRequest struct {
+	Text    string
+	Now     time.Time // in the timezone the text is meant in
+	Profile domain.UserProfile
+}
+
+// QuickAddParser reads quick-add text the rule-based parser could not.
+// Providers built by NewScheduleGenerator implement it next to
+// ScheduleGenerator.
+type QuickAddParser interface {
+	ParseQuickAdd(ctx context.Context, req QuickAddRequest) (*domain.Schedule, error)
+}
+
+var quickAddPromptTemplate = template.Must(template.New("quick-add").Parse(`
+You are a discipline assistant. Turn this note into exactly one calendar item: "{{.Text}}"
+
+It is now {{.Now}} ({{.Weekday}}) in the {{.Timezone}} timezone (UTC{{.Offset}}).
+- Resolve relative dates such as "tomorrow" or "next Friday" from now. Without a date, use the next time the item can happen.
+- Without a time, pick a sensible one between {{.Profile.WakeTime}} and {{.Profile.SleepTime}}, when the user is awake.
+- Without an end time or a duration, make the item one hour long.
+- The title is the note without its date, time, duration and recurrence, e.g. "Lunch with Budi".
+- Only when the note asks for something recurring, set "rrule" to an RFC 5545 rule such as "FREQ=WEEKLY;BYDAY=FR" or "FREQ=MONTHLY;BYMONTHDAY=1", and start the item on its first occurrence.
+- Use ISO 8601 times like "{{.ExampleStart}}".
+
+Respond ONLY with a valid JSON array holding that single item, matching this JSON schema:
+{{.Schema}}
+
+Example:
+[
+	{
+		"title": "Lunch with Budi",
+		"description": "",
+		"start_time": "{{.ExampleStart}}",
+		"end_time": "{{.ExampleEnd}}",
+		"rrule": "FREQ=WEEKLY;BYDAY=FR"
+	}
+]
+`))
+
+func buildQuickAddPrompt(req QuickAddRequest) string {
+	now := req.Now
+	exampleStart := time.Date(now.Year(), now.Month(), now.Day()+1, 12, 30, 0, 0, now.Location())
+
+	var buf bytes.Buffer
+	err := quickAddPromptTemplate.Execute(&buf, map[string]interface{}{
+		"Text":         req.Text,
+		"Profile":      req.Profile,
+		"Now":          now.Format(time.RFC3339),
+		"Weekday":      now.Format("Monday"),
+		"Timezone":     now.Location().String(),
+		"Offset":       now.Format("-07:00"),
+		"ExampleStart": exampleStart.Format(time.RFC3339),
+		"ExampleEnd":   exampleStart.Add(time.Hour).Format(time.RFC3339),
+		"Schema":       scheduleJSONSchema,
+	})
+	if err != nil {
+		// The template is static, so this only happens on a programming error.
+		panic(fmt.Sprintf("render quick-add prompt: %v", err))
+	}
+	return buf.String()
+}
+
+// ParseQuickAdd prompts the model with the note, repairing invalid answers
+// like GenerateScheduleFromText does.
+func (g *llmScheduleGenerator) ParseQuickAdd(ctx context.Context, req QuickAddRequest) (*domain.Schedule, error) {
+	var schedule *domain.Schedule
+	err := g.completeValid(ctx, buildQuickAddPrompt(req), "JSON array", func(reply string) []string {
+		schedules, problems := parseAndValidateSchedules(reply)
+		if len(problems) > 0 {
+			return problems
+		}
+		if len(schedules) != 1 {
+			return []string{fmt.Sprintf("the array must hold exactly one item, got %d", len(schedules))}
+		}
+		schedule = &schedules[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"murim-helper/internal/domain"
+)
+
+// ReplanRequest is an existing day and the user's instruction for changing it
+type ReplanRequest struct {
+	Day         time.Time         // midnight of the day in the user's timezone
+	Schedules   []domain.Schedule // the day as currently planned, sorted by start
+	Instruction string
+	Profile     domain.UserProfile
+}
+
+// DayReplanner proposes changes to an already planned day. Providers built
+// by NewScheduleGenerator implement it next to ScheduleGenerator.
+type DayReplanner interface {
+	ReplanDay(ctx context.Context, req ReplanRequest) (*domain.ReplanDiff, error)
+}
+
+// replanJSONSchema is the contract for replan answers. Existing items are
+// referred to by the "ref" number they were listed with in the prompt.
+const replanJSONSchema = `{
+  "type": "object",
+  "required": ["moves", "deletions", "additions"],
+  "additionalProperties": false,
+  "properties": {
+    "moves": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["ref", "start_time", "end_time"],
+        "additionalProperties": false,
+        "properties": {
+          "ref": {"type": "integer"},
+          "start_time": {"type": "string", "format": "date-time"},
+          "end_time": {"type": "string", "format": "date-time"}
+        }
+      }
+    },
+    "deletions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["ref"],
+        "additionalProperties": false,
+        "properties": {
+          "ref": {"type": "integer"},
+          "reason": {"type": "string"}
+        }
+      }
+    },
+    "additions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["title", "start_time", "end_time"],
+        "additionalProperties": false,
+        "properties": {
+          "title": {"type": "string", "minLength": 1},
+          "description": {"type": "string"},
+          "start_time": {"type": "string", "format": "date-time"},
+          "end_time": {"type": "string", "format": "date-time"}
+        }
+      }
+    }
+  }
+}`
+
+var compiledReplanSchema = func() *jsonSchema {
+	var s jsonSchema
+	if err := json.Unmarshal([]byte(replanJSONSchema), &s); err != nil {
+		panic(fmt.Sprintf("invalid replan JSON schema: %v", err))
+	}
+	return &s
+}()
+
+var replanPromptTemplate = template.Must(template.New("replan").Parse(`
+You are a discipline assistant. The user already planned {{.Date}} ({{.Weekday}}) in the {{.Timezone}} timezone (UTC{{.Offset}}) and wants to change it. It is now {{.Now}}.
+
+The current plan, each item with its ref number:
+{{- range .Items}}
+- ref {{.Ref}}: "{{.Title}}" from {{.Start}} to {{.End}}{{if .Description}} ({{.Description}}){{end}}
+{{- else}}
+- nothing is planned yet
+{{- end}}
+
+The user wakes up at {{.Profile.WakeTime}} and goes to bed at {{.Profile.SleepTime}}.
+
+The user's instruction: "{{.Instruction}}"
+
+Propose the smallest set of changes that follows the instruction:
+- "moves": existing items that get a new start_time and end_time, by ref.
+- "deletions": existing items to drop, by ref, with a short "reason".
+- "additions": new items with title, description, start_time and end_time.
+Items that are not moved or deleted stay as they are, and an item is moved or deleted at most once.
+After the changes no two items may overlap. Use ISO 8601 times like "{{.ExampleStart}}".
+
+Respond ONLY with a valid JSON object matching this JSON schema:
+{{.Schema}}
+
+Example:
+{
+	"moves": [{"ref": 1, "start_time": "{{.ExampleStart}}", "end_time": "{{.ExampleEnd}}"}],
+	"deletions": [{"ref": 2, "reason": "No time left today"}],
+	"additions": []
+}
+`))
+
+type replanPromptItem struct {
+	Ref         int
+	Title       string
+	Description string
+	Start       string
+	End         string
+}
+
+func buildReplanPrompt(req ReplanRequest, now time.Time) string {
+	loc := req.Profile.Location()
+	day := req.Day.In(loc)
+	wake, _ := time.Parse("15:04", req.Profile.WakeTime)
+	exampleStart := time.Date(day.Year(), day.Month(), day.Day(), wake.Hour(), wake.Minute(), 0, 0, loc)
+
+	items := make([]replanPromptItem, len(req.Schedules))
+	for i, s := range req.Schedules {
+		items[i] = replanPromptItem{
+			Ref:         i + 1,
+			Title:       s.Title,
+			Description: s.Description,
+			Start:       s.StartTime.In(loc).Format(time.RFC3339),
+			End:         s.EndTime.In(loc).Format(time.RFC3339),
+		}
+	}
+
+	var buf bytes.Buffer
+	err := replanPromptTemplate.Execute(&buf, map[string]interface{}{
+		"Date":         day.Format("2006-01-02"),
+		"Weekday":      day.Format("Monday"),
+		"Timezone":     req.Profile.Timezone,
+		"Offset":       exampleStart.Format("-07:00"),
+		"Now":          now.In(loc).Format(time.RFC3339),
+		"Items":        items,
+		"Profile":      req.Profile,
+		"Instruction":  req.Instruction,
+		"ExampleStart": exampleStart.Format(time.RFC3339),
+		"ExampleEnd":   exampleStart.Add(time.Hour).Format(time.RFC3339),
+		"Schema":       replanJSONSchema,
+	})
+	if err != nil {
+		// The template is static, so this only happens on a programming error.
+		panic(fmt.Sprintf("render replan prompt: %v", err))
+	}
+	return buf.String()
+}
+
+// ReplanDay prompts the model with the day and the instruction, repairing
+// invalid answers like GenerateScheduleFromText does.
+func (g *llmScheduleGenerator) ReplanDay(ctx context.Context, req ReplanRequest) (*domain.ReplanDiff, error) {
+	var diff *domain.ReplanDiff
+	err := g.completeValid(ctx, buildReplanPrompt(req, time.Now()), "JSON object", func(reply string) []string {
+		var problems []string
+		diff, problems = parseAndValidateReplan(reply, req.Schedules)
+		return problems
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// parseAndValidateReplan turns a model reply into a diff against day,
+// returning the problems to feed back to the model when it is not usable.
+func parseAndValidateReplan(reply string, day []domain.Schedule) (*domain.ReplanDiff, []string) {
+	raw, err := extractJSONObject(reply)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	if problems := validateJSONSchema(compiledReplanSchema, value, "$"); len(problems) > 0 {
+		return nil, problems
+	}
+
+	var answer struct {
+		Moves []struct {
+			Ref       int       `json:"ref"`
+			StartTime time.Time `json:"start_time"`
+			EndTime   time.Time `json:"end_time"`
+		} `json:"moves"`
+		Deletions []struct {
+			Ref    int    `json:"ref"`
+			Reason string `json:"reason"`
+		} `json:"deletions"`
+		Additions json.RawMessage `json:"additions"`
+	}
+	if err := json.Unmarshal(raw, &answer); err != nil {
+		return nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	var problems []string
+	diff := &domain.ReplanDiff{Moves: []domain.ReplanMove{}, Deletions: []domain.ReplanDeletion{}}
+	after := make(map[int]domain.Schedule, len(day))
+	for i, s := range day {
+		after[i+1] = s
+	}
+	touched := make(map[int]string)
+	useRef := func(path string, ref int) bool {
+		if ref < 1 || ref > len(day) {
+			problems = append(problems, fmt.Sprintf("%s: ref %d does not exist", path, ref))
+			return false
+		}
+		if prev, ok := touched[ref]; ok {
+			problems = append(problems, fmt.Sprintf("%s: ref %d was already used by %s", path, ref, prev))
+			return false
+		}
+		touched[ref] = path
+		return true
+	}
+
+	for i, m := range answer.Moves {
+		path := fmt.Sprintf("moves[%d]", i)
+		if !useRef(path, m.Ref) {
+			continue
+		}
+		if !m.EndTime.After(m.StartTime) {
+			problems = append(problems, fmt.Sprintf("%s: end_time must be after start_time", path))
+			continue
+		}
+		s := day[m.Ref-1]
+		diff.Moves = append(diff.Moves, domain.ReplanMove{
+			ScheduleID: s.ID,
+			Title:      s.Title,
+			FromStart:  s.StartTime,
+			FromEnd:    s.EndTime,
+			StartTime:  m.StartTime,
+			EndTime:    m.EndTime,
+		})
+		s.StartTime, s.EndTime = m.StartTime, m.EndTime
+		after[m.Ref] = s
+	}
+
+	for i, d := range answer.Deletions {
+		if !useRef(fmt.Sprintf("deletions[%d]", i), d.Ref) {
+			continue
+		}
+		s := day[d.Ref-1]
+		diff.Deletions = append(diff.Deletions, domain.ReplanDeletion{
+			ScheduleID: s.ID,
+			Title:      s.Title,
+			StartTime:  s.StartTime,
+			Reason:     strings.TrimSpace(d.Reason),
+		})
+		delete(after, d.Ref)
+	}
+
+	diff.Additions = []domain.Schedule{}
+	if len(answer.Additions) > 0 {
+		additions, err := domain.ParseSchedulesFromJSON(string(answer.Additions))
+		if err != nil {
+			return nil, append(problems, err.Error())
+		}
+		for i, a := range additions {
+			if !a.EndTime.After(a.StartTime) {
+				problems = append(problems, fmt.Sprintf("additions[%d]: end_time must be after start_time", i))
+			}
+		}
+		diff.Additions = additions
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	// Overlaps the day already had are not the model's to fix
+	changed := make(map[string]bool)
+	result := append([]domain.Schedule{}, diff.Additions...)
+	for _, a := range diff.Additions {
+		changed[a.ID] = true
+	}
+	for _, m := range diff.Moves {
+		changed[m.ScheduleID] = true
+	}
+	for _, s := range after {
+		result = append(result, s)
+	}
+	overlaps := domain.FindConflicts(result, func(a, b domain.Schedule) bool {
+		return changed[a.ID] || changed[b.ID]
+	})
+	for _, c := range overlaps {
+		problems = append(problems, fmt.Sprintf("after the changes %q (%s) overlaps %q",
+			c.Title, c.Start.Format(time.RFC3339), c.ConflictingTitle))
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return diff, nil
+}
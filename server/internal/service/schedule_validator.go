@@ -0,0 +1,271 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"murim-helper/internal/domain"
+)
+
+// scheduleJSONSchema is the contract every model answer must satisfy. It is
+// sent to the model in the prompt and checked by validateJSONSchema.
+const scheduleJSONSchema = `{
+  "type": "array",
+  "minItems": 1,
+  "items": {
+    "type": "object",
+    "required": ["title", "start_time", "end_time"],
+    "additionalProperties": false,
+    "properties": {
+      "title": {"type": "string", "minLength": 1},
+      "description": {"type": "string"},
+      "start_time": {"type": "string", "format": "date-time"},
+      "end_time": {"type": "string", "format": "date-time"},
+      "repeat_type": {"type": "string"},
+      "repeat_until": {"type": ["string", "null"], "format": "date-time"},
+      "rrule": {"type": "string"}
+    }
+  }
+}`
+
+// jsonSchema is the subset of JSON Schema used by scheduleJSONSchema.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MinLength            *int                   `json:"minLength"`
+	Format               string                 `json:"format"`
+}
+
+// schemaTypes accepts both "type": "string" and "type": ["string", "null"].
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+var compiledScheduleSchema = func() *jsonSchema {
+	var s jsonSchema
+	if err := json.Unmarshal([]byte(scheduleJSONSchema), &s); err != nil {
+		panic(fmt.Sprintf("invalid schedule JSON schema: %v", err))
+	}
+	return &s
+}()
+
+// validateJSONSchema reports every place where value violates schema.
+func validateJSONSchema(schema *jsonSchema, value interface{}, path string) []string {
+	var problems []string
+
+	if len(schema.Type) > 0 && !matchesType(schema.Type, value) {
+		return []string{fmt.Sprintf("%s: expected %s", path, strings.Join(schema.Type, " or "))}
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			problems = append(problems, fmt.Sprintf("%s: expected at least %d item(s)", path, *schema.MinItems))
+		}
+		if schema.Items != nil {
+			for i, item := range v {
+				problems = append(problems, validateJSONSchema(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		for _, key := range schema.Required {
+			if _, ok := v[key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %q", path, key))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			prop, ok := schema.Properties[key]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					problems = append(problems, fmt.Sprintf("%s: unexpected field %q", path, key))
+				}
+				continue
+			}
+			problems = append(problems, validateJSONSchema(prop, v[key], path+"."+key)...)
+		}
+	case string:
+		if schema.MinLength != nil && len(strings.TrimSpace(v)) < *schema.MinLength {
+			problems = append(problems, fmt.Sprintf("%s: must not be empty", path))
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not an RFC 3339 date-time with timezone offset", path, v))
+			}
+		}
+	}
+
+	return problems
+}
+
+func matchesType(types schemaTypes, value interface{}) bool {
+	for _, t := range types {
+		switch t {
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := value.(float64); ok && n == math.Trunc(n) {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractJSONArray pulls the schedule array out of a model reply, tolerating
+// markdown code fences, surrounding prose and a {"schedules": [...]} wrapper.
+func extractJSONArray(content string) ([]byte, error) {
+	content = strings.TrimSpace(content)
+
+	for start := strings.Index(content, "["); start != -1; {
+		dec := json.NewDecoder(strings.NewReader(content[start:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == nil {
+			return raw, nil
+		}
+		next := strings.Index(content[start+1:], "[")
+		if next == -1 {
+			break
+		}
+		start += next + 1
+	}
+
+	if start := strings.Index(content, "{"); start != -1 {
+		var wrapper struct {
+			Schedules json.RawMessage `json:"schedules"`
+		}
+		dec := json.NewDecoder(strings.NewReader(content[start:]))
+		if err := dec.Decode(&wrapper); err == nil && bytes.HasPrefix(bytes.TrimSpace(wrapper.Schedules), []byte("[")) {
+			return wrapper.Schedules, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no JSON array found in response")
+}
+
+// extractJSONObject pulls the first JSON object out of a model reply,
+// tolerating markdown code fences and surrounding prose.
+func extractJSONObject(content string) ([]byte, error) {
+	content = strings.TrimSpace(content)
+
+	for start := strings.Index(content, "{"); start != -1; {
+		dec := json.NewDecoder(strings.NewReader(content[start:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == nil {
+			return raw, nil
+		}
+		next := strings.Index(content[start+1:], "{")
+		if next == -1 {
+			break
+		}
+		start += next + 1
+	}
+
+	return nil, fmt.Errorf("no JSON object found in response")
+}
+
+// parseAndValidateSchedules turns a model reply into schedules, returning
+// the list of problems to feed back to the model when it is not usable.
+func parseAndValidateSchedules(reply string) ([]domain.Schedule, []string) {
+	raw, err := extractJSONArray(reply)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	if problems := validateJSONSchema(compiledScheduleSchema, value, "$"); len(problems) > 0 {
+		return nil, problems
+	}
+
+	schedules, err := domain.ParseSchedulesFromJSON(string(raw))
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	if problems := checkScheduleConsistency(schedules); len(problems) > 0 {
+		return nil, problems
+	}
+	return schedules, nil
+}
+
+// checkScheduleConsistency verifies that a generated day is usable: every
+// item ends after it starts, items are in order without overlapping, and all
+// of them fall on the date the model inferred for the first item.
+func checkScheduleConsistency(schedules []domain.Schedule) []string {
+	var problems []string
+	if len(schedules) == 0 {
+		return problems
+	}
+
+	first := schedules[0].StartTime
+	dayStart := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, first.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	for i, s := range schedules {
+		label := fmt.Sprintf("item %d (%q)", i, s.Title)
+
+		if !s.EndTime.After(s.StartTime) {
+			problems = append(problems, fmt.Sprintf("%s: end_time %s must be after start_time %s",
+				label, s.EndTime.Format(time.RFC3339), s.StartTime.Format(time.RFC3339)))
+		}
+		if s.StartTime.Before(dayStart) || !s.StartTime.Before(dayEnd) || s.EndTime.After(dayEnd) {
+			problems = append(problems, fmt.Sprintf("%s: must be on %s like the first item",
+				label, dayStart.Format("2006-01-02")))
+		}
+
+		if i == 0 {
+			continue
+		}
+		prev := schedules[i-1]
+		if s.StartTime.Before(prev.StartTime) {
+			problems = append(problems, fmt.Sprintf("%s: starts before the previous item %q; items must be in chronological order",
+				label, prev.Title))
+		} else if s.StartTime.Before(prev.EndTime) {
+			problems = append(problems, fmt.Sprintf("%s: overlaps the previous item %q which ends at %s",
+				label, prev.Title, prev.EndTime.Format(time.RFC3339)))
+		}
+	}
+
+	return problems
+}
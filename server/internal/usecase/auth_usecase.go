@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/repository"
+	"murim-helper/pkg/jwt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix marks API keys so they can be told apart from bearer tokens
+const apiKeyPrefix = "mh_"
+
+type AuthUsecase interface {
+	// Authenticate resolves an API key or signed bearer token to the user
+	// and key behind it
+	Authenticate(ctx context.Context, credential string) (*domain.Principal, error)
+	IssueAPIKey(ctx context.Context, userID, name string) (*domain.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID string) error
+	// IssueToken signs a bearer token for a caller authenticated with an API
+	// key. Bearer tokens cannot be exchanged for new ones.
+	IssueToken(ctx context.Context, p domain.Principal) (string, time.Time, error)
+}
+
+type authUsecase struct {
+	repo      repository.UserRepository
+	jwtSecret []byte
+	tokenTTL  time.Duration
+}
+
+// NewAuthUsecase creates the auth usecase. An empty jwtSecret disables bearer
+// tokens so only API keys are accepted.
+func NewAuthUsecase(r repository.UserRepository, jwtSecret string, tokenTTL time.Duration) AuthUsecase {
+	return &authUsecase{repo: r, jwtSecret: []byte(jwtSecret), tokenTTL: tokenTTL}
+}
+
+func (a *authUsecase) Authenticate(ctx context.Context, credential string) (*domain.Principal, error) {
+	credential = strings.TrimSpace(credential)
+	if credential == "" {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if strings.HasPrefix(credential, apiKeyPrefix) {
+		return a.authenticateAPIKey(ctx, credential)
+	}
+	return a.authenticateToken(ctx, credential)
+}
+
+func (a *authUsecase) authenticateAPIKey(ctx context.Context, plaintext string) (*domain.Principal, error) {
+	key, err := a.repo.GetAPIKeyByHash(ctx, hashAPIKey(plaintext))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return nil, domain.ErrAPIKeyRevoked
+	}
+
+	// Usage tracking must never fail the request
+	if err := a.repo.TouchAPIKey(ctx, key.ID, time.Now()); err != nil {
+		log.Printf("[Auth] failed to record api key usage: %v", err)
+	}
+	return &domain.Principal{UserID: key.UserID, KeyID: key.ID}, nil
+}
+
+func (a *authUsecase) authenticateToken(ctx context.Context, token string) (*domain.Principal, error) {
+	if len(a.jwtSecret) == 0 {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	claims, err := jwt.Verify(token, a.jwtSecret, time.Now())
+	if err != nil {
+		if errors.Is(err, jwt.ErrExpiredToken) {
+			return nil, domain.ErrTokenExpired
+		}
+		return nil, domain.ErrInvalidCredentials
+	}
+	if claims.KeyID == "" {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	// Revoking the key, or deleting the user along with their keys, must end
+	// access through tokens issued with it
+	key, err := a.repo.GetAPIKeyByID(ctx, claims.KeyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to look up token key: %w", err)
+	}
+	if key.UserID != claims.Subject {
+		return nil, domain.ErrInvalidCredentials
+	}
+	if key.RevokedAt != nil {
+		return nil, domain.ErrAPIKeyRevoked
+	}
+	return &domain.Principal{UserID: key.UserID, KeyID: key.ID, Bearer: true}, nil
+}
+
+// IssueAPIKey creates a key and returns its plaintext, which is never stored
+func (a *authUsecase) IssueAPIKey(ctx context.Context, userID, name string) (*domain.APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := domain.APIKey{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    plaintext[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(plaintext),
+		CreatedAt: time.Now(),
+	}
+	if err := a.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to save api key: %w", err)
+	}
+	return &key, plaintext, nil
+}
+
+func (a *authUsecase) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	return a.repo.ListAPIKeys(ctx, userID)
+}
+
+func (a *authUsecase) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	if strings.TrimSpace(keyID) == "" {
+		return errors.New("id cannot be empty")
+	}
+
+	key, err := a.repo.GetAPIKeyByID(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	if key.UserID != userID {
+		return domain.ErrForbidden
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return a.repo.RevokeAPIKey(ctx, userID, keyID, time.Now())
+}
+
+func (a *authUsecase) IssueToken(ctx context.Context, p domain.Principal) (string, time.Time, error) {
+	if len(a.jwtSecret) == 0 {
+		return "", time.Time{}, domain.ErrTokensDisabled
+	}
+	if p.Bearer || p.KeyID == "" {
+		return "", time.Time{}, domain.ErrAPIKeyRequired
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(a.tokenTTL)
+	token, err := jwt.Sign(jwt.Claims{
+		Subject:   p.UserID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		Issuer:    "murim-helper",
+		KeyID:     p.KeyID,
+	}, a.jwtSecret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,56 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"murim-helper/internal/domain"
+	"murim-helper/internal/repository"
+	"murim-helper/internal/usecase"
+)
+
+func TestTokensEndWithTheirAPIKey(t *testing.T) {
+	ctx := context.Background()
+	auth := usecase.NewAuthUsecase(repository.NewMemoryRepo(), "secret", time.Hour)
+
+	key, plaintext, err := auth.IssueAPIKey(ctx, domain.DefaultUserID, "laptop")
+	if err != nil {
+		t.Fatalf("IssueAPIKey: %v", err)
+	}
+	viaKey, err := auth.Authenticate(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("Authenticate with the key: %v", err)
+	}
+	if viaKey.UserID != domain.DefaultUserID || viaKey.KeyID != key.ID || viaKey.Bearer {
+		t.Errorf("key principal = %+v", viaKey)
+	}
+
+	token, _, err := auth.IssueToken(ctx, *viaKey)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+	viaToken, err := auth.Authenticate(ctx, token)
+	if err != nil {
+		t.Fatalf("Authenticate with the token: %v", err)
+	}
+	if viaToken.UserID != domain.DefaultUserID || viaToken.KeyID != key.ID || !viaToken.Bearer {
+		t.Errorf("token principal = %+v", viaToken)
+	}
+
+	// A token cannot mint its successor
+	if _, _, err := auth.IssueToken(ctx, *viaToken); !errors.Is(err, domain.ErrAPIKeyRequired) {
+		t.Errorf("IssueToken with a token: got %v, want domain.ErrAPIKeyRequired", err)
+	}
+	if _, _, err := auth.IssueToken(ctx, domain.Principal{UserID: domain.DefaultUserID}); !errors.Is(err, domain.ErrAPIKeyRequired) {
+		t.Errorf("IssueToken without a key: got %v, want domain.ErrAPIKeyRequired", err)
+	}
+
+	if err := auth.RevokeAPIKey(ctx, domain.DefaultUserID, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if _, err := auth.Authenticate(ctx, token); !errors.Is(err, domain.ErrAPIKeyRevoked) {
+		t.Errorf("Authenticate with a token of a revoked key: got %v, want domain.ErrAPIKeyRevoked", err)
+	}
+}
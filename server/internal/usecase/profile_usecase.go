@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/repository"
+	"time"
+)
+
+type ProfileUsecase interface {
+	GetProfile(ctx context.Context, userID string) (*domain.UserProfile, error)
+	UpdateProfile(ctx context.Context, userID string, profile domain.UserProfile) (*domain.UserProfile, error)
+}
+
+type profileUsecase struct {
+	repo repository.ProfileRepository
+}
+
+func NewProfileUsecase(r repository.ProfileRepository) ProfileUsecase {
+	return &profileUsecase{repo: r}
+}
+
+func (p *profileUsecase) GetProfile(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	return loadProfile(ctx, p.repo, userID)
+}
+
+func (p *profileUsecase) UpdateProfile(ctx context.Context, userID string, profile domain.UserProfile) (*domain.UserProfile, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	profile.UserID = userID
+	profile.UpdatedAt = time.Now()
+	normalizeProfile(&profile)
+
+	if err := p.repo.SaveProfile(ctx, profile); err != nil {
+		return nil, fmt.Errorf("failed to save profile: %w", err)
+	}
+	return &profile, nil
+}
+
+// loadProfile returns the stored profile or the default routine when the
+// user has not saved one yet.
+func loadProfile(ctx context.Context, repo repository.ProfileRepository, userID string) (*domain.UserProfile, error) {
+	profile, err := repo.GetProfile(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			def := domain.DefaultUserProfile()
+			def.UserID = userID
+			return &def, nil
+		}
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	normalizeProfile(profile)
+	return profile, nil
+}
+
+func normalizeProfile(profile *domain.UserProfile) {
+	if profile.Habits == nil {
+		profile.Habits = []domain.Habit{}
+	}
+	if profile.BlockedTimes == nil {
+		profile.BlockedTimes = []domain.TimeBlock{}
+	}
+}
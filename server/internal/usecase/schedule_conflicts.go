@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"time"
+)
+
+// conflictHorizon bounds how far ahead the occurrences of a recurring
+// schedule are checked for conflicts
+const conflictHorizon = 90 * 24 * time.Hour
+
+// conflictLookback is how long before a window existing schedules are
+// loaded, so ones that started earlier but still run are seen
+const conflictLookback = 24 * time.Hour
+
+// checkConflicts finds the existing schedules that candidates would overlap,
+// and the candidates that overlap each other. Rows of a series being edited
+// are left out since the candidates replace them. With strict set any
+// conflict is returned as a *domain.ConflictError.
+func (s *scheduleUsecase) checkConflicts(ctx context.Context, userID string, candidates []domain.Schedule, strict bool) ([]domain.Conflict, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var from, to time.Time
+	var expanded []domain.Schedule
+	isCandidate := make(map[string]bool)
+	replaced := make(map[string]bool)
+	for i, c := range candidates {
+		start, end := conflictWindow(c, now)
+		if i == 0 || start.Before(from) {
+			from = start
+		}
+		if i == 0 || end.After(to) {
+			to = end
+		}
+
+		occurrences := []domain.Schedule{c}
+		if c.RRule != "" {
+			var err error
+			if occurrences, err = c.Occurrences(start, end, nil); err != nil {
+				return nil, err
+			}
+		}
+		for _, occ := range occurrences {
+			isCandidate[occ.ID] = true
+		}
+		expanded = append(expanded, occurrences...)
+		if c.SeriesID != "" {
+			replaced[c.SeriesID] = true
+		}
+	}
+	if len(expanded) == 0 {
+		return nil, nil
+	}
+
+	lookback := from.Add(-conflictLookback)
+	existing, err := s.listInRange(ctx, userID, dto.ScheduleFilter{StartAfter: &lookback, StartBefore: &to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedules for conflict check: %w", err)
+	}
+	for _, e := range existing {
+		if !replaced[e.SeriesID] {
+			expanded = append(expanded, e)
+		}
+	}
+
+	conflicts := domain.FindConflicts(expanded, func(a, b domain.Schedule) bool {
+		return isCandidate[a.ID] || isCandidate[b.ID]
+	})
+	for i, c := range conflicts {
+		// Name the new schedule first
+		if !isCandidate[c.ScheduleID] {
+			conflicts[i].ScheduleID, conflicts[i].ConflictingID = c.ConflictingID, c.ScheduleID
+			conflicts[i].Title, conflicts[i].ConflictingTitle = c.ConflictingTitle, c.Title
+		}
+	}
+
+	if strict && len(conflicts) > 0 {
+		return conflicts, &domain.ConflictError{Conflicts: conflicts}
+	}
+	return conflicts, nil
+}
+
+// conflictWindow is the time a candidate is checked over: a one-off
+// schedule's own time, or the next conflictHorizon of a recurring one
+func conflictWindow(c domain.Schedule, now time.Time) (time.Time, time.Time) {
+	if c.RRule == "" {
+		return c.StartTime, c.EndTime
+	}
+	start := c.StartTime
+	if now.After(start) {
+		start = now
+	}
+	end := start.Add(conflictHorizon)
+	if c.RepeatUntil != nil && c.RepeatUntil.Before(end) {
+		end = c.RepeatUntil.Add(c.EndTime.Sub(c.StartTime))
+	}
+	if !start.Before(end) {
+		end = start
+	}
+	return start, end
+}
+
+// GetConflicts lists every overlapping pair of schedules and occurrences
+// starting in [from, to)
+func (s *scheduleUsecase) GetConflicts(ctx context.Context, userID string, from, to time.Time) ([]domain.Conflict, error) {
+	lookback := from.Add(-conflictLookback)
+	schedules, err := s.listInRange(ctx, userID, dto.ScheduleFilter{StartAfter: &lookback, StartBefore: &to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+	return domain.FindConflicts(schedules, func(a, b domain.Schedule) bool {
+		// Pairs that end before the window were only loaded for context
+		return a.EndTime.After(from) && b.EndTime.After(from)
+	}), nil
+}
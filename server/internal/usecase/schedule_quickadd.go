@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/service"
+	"murim-helper/pkg/quickadd"
+	"strings"
+	"time"
+)
+
+// quickAddRules is reported as the provider when the rule-based parser read
+// the text and no AI was involved
+const quickAddRules = "rules"
+
+// QuickAdd turns one line of text into a single, possibly recurring,
+// schedule in loc. The offline rule-based parser is tried first and the AI
+// chain only reads what it could not. With dryRun set the schedule is only
+// returned, not saved.
+func (s *scheduleUsecase) QuickAdd(ctx context.Context, userID, text string, loc *time.Location, strict, dryRun bool) (*domain.GenerationResult, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("text cannot be empty")
+	}
+
+	now := time.Now().In(loc)
+	result := &domain.GenerationResult{Provider: quickAddRules}
+
+	var schedule domain.Schedule
+	item, err := quickadd.Parse(text, now)
+	switch {
+	case err == nil:
+		schedule = domain.Schedule{
+			Title:     item.Title,
+			StartTime: item.Start,
+			EndTime:   item.End,
+			RRule:     item.RRule,
+		}
+	case errors.Is(err, quickadd.ErrUnrecognized):
+		profile, err := loadProfile(ctx, s.repo, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Add timeout for AI call
+		aiCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+
+		parsed, provider, attempts, err := s.ai.QuickAdd(aiCtx, service.QuickAddRequest{Text: text, Now: now, Profile: *profile})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quick-add text: %w", err)
+		}
+		schedule = *parsed
+		result.Provider, result.Attempts = provider, attempts
+	default:
+		return nil, err
+	}
+
+	schedule.Timezone = loc.String()
+	schedules := []domain.Schedule{schedule}
+	if err := s.prepareNew(ctx, userID, schedules); err != nil {
+		return nil, err
+	}
+
+	conflicts, err := s.checkConflicts(ctx, userID, schedules, strict)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := s.repo.SaveMany(ctx, schedules); err != nil {
+			return nil, fmt.Errorf("failed to save schedule: %w", err)
+		}
+	}
+
+	result.Schedules = schedules
+	result.Conflicts = conflicts
+	return result, nil
+}
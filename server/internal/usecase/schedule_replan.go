@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"murim-helper/internal/service"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReplanDay asks the AI how to change the day starting at day (midnight in
+// the caller's timezone) to follow instruction. The proposed diff is saved
+// until the user accepts or rejects it.
+func (s *scheduleUsecase) ReplanDay(ctx context.Context, userID string, day time.Time, instruction string) (*domain.ReplanProposal, error) {
+	if strings.TrimSpace(instruction) == "" {
+		return nil, errors.New("instruction cannot be empty")
+	}
+
+	profile, err := loadProfile(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+	schedules, err := s.listInRange(ctx, userID, dto.ScheduleFilter{StartAfter: &day, StartBefore: &end})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	// Add timeout for AI call
+	aiCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	diff, provider, attempts, err := s.ai.Replan(aiCtx, service.ReplanRequest{
+		Day:         day,
+		Schedules:   schedules,
+		Instruction: instruction,
+		Profile:     *profile,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replan day: %w", err)
+	}
+
+	now := time.Now()
+	proposal := domain.ReplanProposal{
+		Token:       uuid.NewString(),
+		UserID:      userID,
+		Day:         day,
+		Instruction: instruction,
+		Diff:        *diff,
+		Provider:    provider,
+		Attempts:    attempts,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(previewTTL),
+	}
+	if err := s.repo.SaveReplan(ctx, proposal); err != nil {
+		return nil, fmt.Errorf("failed to save replan: %w", err)
+	}
+	return &proposal, nil
+}
+
+// AcceptReplan applies a proposed diff: moved occurrences of recurring
+// schedules become exceptions, deleted ones are cancelled and additions are
+// created as one-off schedules. The diff is saved in one transaction with
+// consuming the proposal, so one that fails to apply can be accepted again.
+// A diff whose schedules were changed after it was proposed is rejected with
+// domain.ErrStaleReplan.
+func (s *scheduleUsecase) AcceptReplan(ctx context.Context, userID, token string) (*domain.ReplanProposal, error) {
+	proposal, err := s.getReplan(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var change domain.SeriesChange
+	for _, m := range proposal.Diff.Moves {
+		t, err := s.replanTarget(ctx, userID, m.ScheduleID, m.FromStart)
+		if err != nil {
+			return nil, err
+		}
+		moved := *t.occ
+		moved.StartTime, moved.EndTime = m.StartTime, m.EndTime
+		if t.exc != nil {
+			t.exc.SetOverrides(t.row.Occurrence(t.start, nil), moved)
+			change.Exceptions = append(change.Exceptions, *t.exc)
+			continue
+		}
+		change.Update = append(change.Update, moved)
+	}
+	for _, d := range proposal.Diff.Deletions {
+		t, err := s.replanTarget(ctx, userID, d.ScheduleID, d.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		if t.exc != nil {
+			t.exc.Cancelled = true
+			change.Exceptions = append(change.Exceptions, *t.exc)
+			continue
+		}
+		change.Delete = append(change.Delete, t.row.ID)
+	}
+	if len(proposal.Diff.Additions) > 0 {
+		if err := s.prepareNew(ctx, userID, proposal.Diff.Additions); err != nil {
+			return nil, err
+		}
+		change.Insert = proposal.Diff.Additions
+	}
+
+	if err := s.repo.AcceptReplan(ctx, userID, token, change); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("replan %s was already accepted or rejected: %w", token, err)
+		}
+		return nil, fmt.Errorf("failed to apply replan: %w", err)
+	}
+	return proposal, nil
+}
+
+// RejectReplan discards a proposed diff
+func (s *scheduleUsecase) RejectReplan(ctx context.Context, userID, token string) error {
+	if strings.TrimSpace(token) == "" {
+		return errors.New("token cannot be empty")
+	}
+	if err := s.repo.DeleteReplan(ctx, userID, token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("replan %s not found or expired: %w", token, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *scheduleUsecase) getReplan(ctx context.Context, userID, token string) (*domain.ReplanProposal, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, errors.New("token cannot be empty")
+	}
+	profile, err := loadProfile(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := s.repo.GetReplan(ctx, userID, token, profile.Location())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("replan %s not found or expired: %w", token, err)
+		}
+		return nil, fmt.Errorf("failed to get replan: %w", err)
+	}
+	return proposal, nil
+}
+
+// replanTarget loads a schedule or occurrence the diff touches and checks
+// it is still a single item starting where it did when the diff was proposed
+func (s *scheduleUsecase) replanTarget(ctx context.Context, userID, id string, start time.Time) (*editTarget, error) {
+	t, err := s.resolveTarget(ctx, userID, id, "")
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s no longer exists", domain.ErrStaleReplan, id)
+		}
+		return nil, err
+	}
+	if t.scope == domain.ScopeSeries {
+		return nil, fmt.Errorf("%w: %q became recurring", domain.ErrStaleReplan, t.row.Title)
+	}
+	if !t.occ.StartTime.Equal(start) {
+		return nil, fmt.Errorf("%w: %q was moved", domain.ErrStaleReplan, t.occ.Title)
+	}
+	return t, nil
+}
@@ -0,0 +1,129 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"murim-helper/internal/domain"
+	"murim-helper/internal/repository"
+	"murim-helper/internal/usecase"
+)
+
+func saveReplan(t *testing.T, repo repository.ScheduleRepository, diff domain.ReplanDiff) string {
+	t.Helper()
+	proposal := domain.ReplanProposal{
+		Token:     "replan-" + time.Now().Format(time.RFC3339Nano),
+		UserID:    domain.DefaultUserID,
+		Day:       time.Now().UTC().Truncate(24 * time.Hour),
+		Diff:      diff,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := repo.SaveReplan(context.Background(), proposal); err != nil {
+		t.Fatalf("SaveReplan: %v", err)
+	}
+	return proposal.Token
+}
+
+func TestAcceptReplan(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	uc := usecase.NewScheduleUsecase(repo, nil)
+
+	start := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+	saveOneOff(t, repo, "lunch", "Lunch", start, time.Hour)
+	daily := domain.Schedule{
+		ID:         "standup",
+		SeriesID:   "standup",
+		UserID:     domain.DefaultUserID,
+		Title:      "Standup",
+		StartTime:  start.Add(-3 * time.Hour),
+		EndTime:    start.Add(-150 * time.Minute),
+		RepeatType: "daily",
+		RRule:      "FREQ=DAILY",
+		Timezone:   "UTC",
+	}
+	if err := repo.SaveMany(ctx, []domain.Schedule{daily}); err != nil {
+		t.Fatalf("SaveMany: %v", err)
+	}
+	standupStart := daily.StartTime.AddDate(0, 0, 1)
+
+	token := saveReplan(t, repo, domain.ReplanDiff{
+		Moves: []domain.ReplanMove{{
+			ScheduleID: "lunch",
+			Title:      "Lunch",
+			FromStart:  start,
+			FromEnd:    start.Add(time.Hour),
+			StartTime:  start.Add(time.Hour),
+			EndTime:    start.Add(2 * time.Hour),
+		}},
+		Deletions: []domain.ReplanDeletion{{
+			ScheduleID: domain.OccurrenceID(daily.ID, standupStart),
+			Title:      "Standup",
+			StartTime:  standupStart,
+		}},
+		Additions: []domain.Schedule{{
+			Title:     "Walk",
+			StartTime: start.Add(3 * time.Hour),
+			EndTime:   start.Add(4 * time.Hour),
+		}},
+	})
+
+	proposal, err := uc.AcceptReplan(ctx, domain.DefaultUserID, token)
+	if err != nil {
+		t.Fatalf("AcceptReplan: %v", err)
+	}
+
+	lunch, err := repo.GetByID(ctx, domain.DefaultUserID, "lunch")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !lunch.StartTime.Equal(start.Add(time.Hour)) || !lunch.EndTime.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("lunch is at %v - %v, want it an hour later", lunch.StartTime, lunch.EndTime)
+	}
+	if lunch.Title != "Lunch" || lunch.Description != "Lunch notes" {
+		t.Errorf("lunch title, description = %q, %q", lunch.Title, lunch.Description)
+	}
+
+	exc, err := repo.GetException(ctx, daily.ID, standupStart)
+	if err != nil || !exc.Cancelled {
+		t.Errorf("GetException: got %+v, %v, want the standup cancelled", exc, err)
+	}
+
+	if len(proposal.Diff.Additions) != 1 || proposal.Diff.Additions[0].ID == "" {
+		t.Fatalf("additions = %+v, want one saved schedule", proposal.Diff.Additions)
+	}
+	if _, err := repo.GetByID(ctx, domain.DefaultUserID, proposal.Diff.Additions[0].ID); err != nil {
+		t.Errorf("GetByID of the addition: %v", err)
+	}
+
+	if _, err := uc.AcceptReplan(ctx, domain.DefaultUserID, token); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("accepting twice: got %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestAcceptStaleReplanKeepsProposal(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	uc := usecase.NewScheduleUsecase(repo, nil)
+
+	start := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+	saveOneOff(t, repo, "lunch", "Lunch", start, time.Hour)
+	token := saveReplan(t, repo, domain.ReplanDiff{
+		Moves: []domain.ReplanMove{{
+			ScheduleID: "lunch",
+			FromStart:  start.Add(-time.Hour),
+			StartTime:  start.Add(time.Hour),
+			EndTime:    start.Add(2 * time.Hour),
+		}},
+	})
+
+	if _, err := uc.AcceptReplan(ctx, domain.DefaultUserID, token); !errors.Is(err, domain.ErrStaleReplan) {
+		t.Fatalf("AcceptReplan: got %v, want domain.ErrStaleReplan", err)
+	}
+	if _, err := repo.GetReplan(ctx, domain.DefaultUserID, token, time.UTC); err != nil {
+		t.Errorf("GetReplan after a stale accept: %v", err)
+	}
+}
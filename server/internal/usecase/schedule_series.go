@@ -0,0 +1,371 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"murim-helper/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// editTarget is what an update, delete or done request points at: a row,
+// and for occurrence IDs the occurrence of that row.
+type editTarget struct {
+	row   *domain.Schedule
+	occ   *domain.Schedule          // the occurrence as currently shown, or the row itself
+	start time.Time                 // the start the rule generated for occ
+	exc   *domain.ScheduleException // nil unless the ID is an occurrence ID
+	scope domain.EditScope          // resolved scope, empty for one-off rows
+}
+
+// resolveTarget loads the row or occurrence behind id and resolves the
+// default scope: "occurrence" for occurrence IDs and "series" for the ID of
+// a recurring row. One-off rows ignore the scope.
+func (s *scheduleUsecase) resolveTarget(ctx context.Context, userID, id string, scope domain.EditScope) (*editTarget, error) {
+	if rowID, start, ok := domain.ParseOccurrenceID(id); ok {
+		row, occ, exc, err := s.getOccurrence(ctx, userID, rowID, start)
+		if err != nil {
+			return nil, err
+		}
+		if scope == "" {
+			scope = domain.ScopeOccurrence
+		}
+		return &editTarget{row: row, occ: occ, start: start, exc: exc, scope: scope}, nil
+	}
+
+	row, err := s.repo.GetByID(ctx, userID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("schedule with id %s not found: %w", id, err)
+		}
+		return nil, fmt.Errorf("failed to get schedule by ID: %w", err)
+	}
+
+	target := &editTarget{row: row, occ: row, start: row.StartTime}
+	if row.RRule == "" {
+		return target, nil
+	}
+	switch scope {
+	case "":
+		scope = domain.ScopeSeries
+	case domain.ScopeOccurrence:
+		return nil, fmt.Errorf("%w: scope=occurrence needs an occurrence ID", domain.ErrInvalidScope)
+	}
+	target.scope = scope
+	return target, nil
+}
+
+// seriesEdit is an update expressed relative to the occurrence it was made
+// on, so it can be replayed on every row of a series.
+type seriesEdit struct {
+	title       *string
+	description *string
+	shift       time.Duration  // how far starts move
+	duration    *time.Duration // new length of each occurrence
+	rrule       *string        // new rule, "" to stop repeating
+}
+
+// newSeriesEdit compares updated with the occurrence it was made on. Empty
+// fields of updated keep their current value.
+func newSeriesEdit(target domain.Schedule, rowRule string, updated domain.Schedule) (seriesEdit, error) {
+	var edit seriesEdit
+
+	if updated.Title != "" && updated.Title != target.Title {
+		edit.title = &updated.Title
+	}
+	if updated.Description != "" && updated.Description != target.Description {
+		edit.description = &updated.Description
+	}
+	if !updated.StartTime.IsZero() {
+		edit.shift = updated.StartTime.Sub(target.StartTime)
+	} else {
+		updated.StartTime = target.StartTime
+	}
+	if !updated.EndTime.IsZero() {
+		if d := updated.EndTime.Sub(updated.StartTime); d != target.EndTime.Sub(target.StartTime) {
+			edit.duration = &d
+		}
+	}
+
+	if updated.RRule != "" || updated.RepeatType != "" {
+		updated.StartTime = target.StartTime
+		if err := updated.NormalizeRecurrence(); err != nil {
+			return edit, err
+		}
+		if updated.RRule != rowRule {
+			edit.rrule = &updated.RRule
+		}
+	}
+	return edit, nil
+}
+
+// apply replays the edit on one row of the series
+func (e seriesEdit) apply(row domain.Schedule) (domain.Schedule, error) {
+	duration := row.EndTime.Sub(row.StartTime)
+	if e.duration != nil {
+		duration = *e.duration
+	}
+	if e.title != nil {
+		row.Title = *e.title
+	}
+	if e.description != nil {
+		row.Description = *e.description
+	}
+	row.StartTime = row.StartTime.Add(e.shift)
+	row.EndTime = row.StartTime.Add(duration)
+	if e.rrule != nil {
+		row.RRule = *e.rrule
+		row.RepeatType = ""
+		row.RepeatUntil = nil
+	}
+	return row, row.NormalizeRecurrence()
+}
+
+// updateSeries replays the edit on every row of the series. A new rule
+// replaces the whole series, so it is set on the first row and the rows
+// split off later are dropped.
+func (s *scheduleUsecase) updateSeries(ctx context.Context, userID string, t *editTarget, updated domain.Schedule, strict bool) ([]domain.Conflict, error) {
+	rows, err := s.repo.GetSeriesRows(ctx, userID, t.row.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := newSeriesEdit(*t.occ, t.row.RRule, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	var change domain.SeriesChange
+	for i, row := range rows {
+		if edit.rrule != nil && i > 0 {
+			change.Delete = append(change.Delete, row.ID)
+			continue
+		}
+		edited, err := edit.apply(row)
+		if err != nil {
+			return nil, err
+		}
+		change.Update = append(change.Update, edited)
+		if edit.shift != 0 {
+			change.Move = append(change.Move, domain.ExceptionMove{FromID: row.ID, ToID: row.ID, Shift: edit.shift})
+		}
+	}
+	return s.saveSeriesChange(ctx, userID, change, strict)
+}
+
+// updateFollowing splits the row at the target occurrence: the row keeps the
+// occurrences before it and a new row in the same series takes the rest with
+// the edit applied. Rows split off later in the series are edited too, or
+// dropped when the rule changes.
+func (s *scheduleUsecase) updateFollowing(ctx context.Context, userID string, t *editTarget, updated domain.Schedule, strict bool) ([]domain.Conflict, error) {
+	rows, err := s.repo.GetSeriesRows(ctx, userID, t.row.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := newSeriesEdit(*t.occ, t.row.RRule, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	head, tail, err := splitRow(*t.row, t.start)
+	if err != nil {
+		return nil, err
+	}
+	tail, err = edit.apply(tail)
+	if err != nil {
+		return nil, err
+	}
+
+	var change domain.SeriesChange
+	if head == nil {
+		// The target is the first occurrence of the row, so the row itself
+		// becomes the tail
+		tail.ID = t.row.ID
+		change.Update = append(change.Update, tail)
+		if edit.shift != 0 {
+			change.Move = append(change.Move, domain.ExceptionMove{FromID: t.row.ID, ToID: t.row.ID, Shift: edit.shift})
+		}
+	} else {
+		change.Update = append(change.Update, *head)
+		change.Insert = append(change.Insert, tail)
+		change.Move = append(change.Move, domain.ExceptionMove{FromID: t.row.ID, ToID: tail.ID, Since: t.start, Shift: edit.shift})
+	}
+
+	for _, row := range laterRows(rows, *t.row) {
+		if edit.rrule != nil {
+			change.Delete = append(change.Delete, row.ID)
+			continue
+		}
+		edited, err := edit.apply(row)
+		if err != nil {
+			return nil, err
+		}
+		change.Update = append(change.Update, edited)
+		if edit.shift != 0 {
+			change.Move = append(change.Move, domain.ExceptionMove{FromID: row.ID, ToID: row.ID, Shift: edit.shift})
+		}
+	}
+	return s.saveSeriesChange(ctx, userID, change, strict)
+}
+
+// saveSeriesChange checks the rows a change writes for conflicts before
+// applying it
+func (s *scheduleUsecase) saveSeriesChange(ctx context.Context, userID string, change domain.SeriesChange, strict bool) ([]domain.Conflict, error) {
+	rows := append(append([]domain.Schedule{}, change.Update...), change.Insert...)
+	conflicts, err := s.checkConflicts(ctx, userID, rows, strict)
+	if err != nil {
+		return conflicts, err
+	}
+	return conflicts, s.repo.ApplySeriesChange(ctx, userID, change)
+}
+
+// deleteFollowing ends the series before the target occurrence
+func (s *scheduleUsecase) deleteFollowing(ctx context.Context, userID string, t *editTarget) error {
+	rows, err := s.repo.GetSeriesRows(ctx, userID, t.row.SeriesID)
+	if err != nil {
+		return err
+	}
+	head, _, err := splitRow(*t.row, t.start)
+	if err != nil {
+		return err
+	}
+
+	var change domain.SeriesChange
+	if head == nil {
+		change.Delete = append(change.Delete, t.row.ID)
+	} else {
+		change.Update = append(change.Update, *head)
+	}
+	for _, row := range laterRows(rows, *t.row) {
+		change.Delete = append(change.Delete, row.ID)
+	}
+	return s.repo.ApplySeriesChange(ctx, userID, change)
+}
+
+// deleteSeries removes every row of the series
+func (s *scheduleUsecase) deleteSeries(ctx context.Context, userID string, t *editTarget) error {
+	rows, err := s.repo.GetSeriesRows(ctx, userID, t.row.SeriesID)
+	if err != nil {
+		return err
+	}
+	var change domain.SeriesChange
+	for _, row := range rows {
+		change.Delete = append(change.Delete, row.ID)
+	}
+	return s.repo.ApplySeriesChange(ctx, userID, change)
+}
+
+// maxDoneWindow bounds how many days of occurrences one request marks as
+// done, since each gets its own exception
+const maxDoneWindow = 366 * 24 * time.Hour
+
+// setDoneFrom marks the occurrences of the series from the target on (or
+// from the start for scope=series) as done or undone. Future occurrences
+// cannot be done yet, so it stops at the target or now, whichever is later.
+func (s *scheduleUsecase) setDoneFrom(ctx context.Context, userID string, t *editTarget, done bool) error {
+	rows, err := s.repo.GetSeriesRows(ctx, userID, t.row.SeriesID)
+	if err != nil {
+		return err
+	}
+
+	from := rows[0].StartTime
+	if t.scope == domain.ScopeFollowing {
+		from = t.start
+	}
+	to := time.Now()
+	if t.start.After(to) {
+		to = t.start
+	}
+	to = to.Add(time.Second)
+	if to.Sub(from) > maxDoneWindow {
+		return fmt.Errorf("%w: scope=%s would mark every occurrence since %s, use scope=following on an occurrence less than 366 days ago",
+			domain.ErrRangeTooLarge, t.scope, from.Format("2006-01-02"))
+	}
+
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	existing, err := s.repo.GetExceptions(ctx, ids, from, to)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]domain.ScheduleException, len(existing))
+	for _, exc := range existing {
+		byKey[domain.OccurrenceID(exc.ScheduleID, exc.OccurrenceStart)] = exc
+	}
+
+	var exceptions []domain.ScheduleException
+	for _, row := range rows {
+		rule, err := row.Recurrence()
+		if err != nil || rule == nil {
+			continue
+		}
+		for _, start := range rule.Between(row.LocalStart(), from, to) {
+			exc, ok := byKey[domain.OccurrenceID(row.ID, start)]
+			if !ok {
+				exc = domain.ScheduleException{ScheduleID: row.ID, OccurrenceStart: start}
+			}
+			if exc.Cancelled {
+				continue
+			}
+			exc.IsDone = done
+			exceptions = append(exceptions, exc)
+		}
+	}
+	return s.repo.SaveExceptions(ctx, exceptions)
+}
+
+// splitRow splits a recurring row at the occurrence starting at split. head
+// keeps the earlier occurrences and is nil when split is the row's first
+// occurrence; tail is a new row in the same series with the rest, keeping
+// what is left of COUNT.
+func splitRow(row domain.Schedule, split time.Time) (*domain.Schedule, domain.Schedule, error) {
+	rule, err := row.Recurrence()
+	if err != nil {
+		return nil, domain.Schedule{}, err
+	}
+	split = split.In(row.Location())
+	earlier := rule.Between(row.LocalStart(), row.StartTime, split)
+
+	tailRule := *rule
+	if tailRule.Count > 0 {
+		tailRule.Count -= len(earlier)
+	}
+	tail := row
+	tail.ID = uuid.NewString()
+	tail.StartTime = split
+	tail.EndTime = split.Add(row.EndTime.Sub(row.StartTime))
+	tail.IsDone = false
+	tail.CreatedAt = time.Now()
+	tail.RRule = tailRule.String()
+	if err := tail.NormalizeRecurrence(); err != nil {
+		return nil, domain.Schedule{}, err
+	}
+
+	if len(earlier) == 0 {
+		return nil, tail, nil
+	}
+
+	head := row
+	headRule := *rule
+	until := split.Add(-time.Second)
+	headRule.SetUntil(&until)
+	head.RRule = headRule.String()
+	if err := head.NormalizeRecurrence(); err != nil {
+		return nil, domain.Schedule{}, err
+	}
+	return &head, tail, nil
+}
+
+// laterRows returns the rows of a series that were split off after row
+func laterRows(rows []domain.Schedule, row domain.Schedule) []domain.Schedule {
+	var later []domain.Schedule
+	for _, r := range rows {
+		if r.ID != row.ID && r.StartTime.After(row.StartTime) {
+			later = append(later, r)
+		}
+	}
+	return later
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"time"
+)
+
+// autoPlaceHorizon is how far ahead a task without a deadline is placed
+const autoPlaceHorizon = 7 * 24 * time.Hour
+
+// maxAutoPlaceWindow bounds how far ahead a task is placed however distant
+// its deadline, since every series is expanded over the whole window
+const maxAutoPlaceWindow = 31 * 24 * time.Hour
+
+// placementStep is the grid auto-placed tasks start on when placed from now
+const placementStep = 15 * time.Minute
+
+// GetFreeSlots lists the free periods of at least minDuration in
+// [from, to), within the user's waking hours
+func (s *scheduleUsecase) GetFreeSlots(ctx context.Context, userID string, from, to time.Time, minDuration time.Duration) ([]domain.TimeSlot, error) {
+	profile, err := loadProfile(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lookback := from.Add(-conflictLookback)
+	busy, err := s.listInRange(ctx, userID, dto.ScheduleFilter{StartAfter: &lookback, StartBefore: &to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+	return domain.FreeSlots(busy, from, to, *profile, minDuration), nil
+}
+
+// AutoPlace puts the task in the best free slot before its deadline, or
+// within a week when it has none. Distant deadlines are cut to
+// maxAutoPlaceWindow. With dryRun set the placement is only
+// returned, not saved.
+func (s *scheduleUsecase) AutoPlace(ctx context.Context, userID string, task domain.PlacementTask, dryRun bool) (*domain.Schedule, error) {
+	if task.Duration <= 0 {
+		return nil, errors.New("duration must be positive")
+	}
+
+	from := task.Earliest
+	if now := time.Now(); from.Before(now) {
+		from = now.Truncate(placementStep)
+		if from.Before(now) {
+			from = from.Add(placementStep)
+		}
+	}
+	to := from.Add(autoPlaceHorizon)
+	if task.Deadline != nil {
+		to = *task.Deadline
+		if limit := from.Add(maxAutoPlaceWindow); to.After(limit) {
+			to = limit
+		}
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: the deadline has passed", domain.ErrNoFreeSlot)
+	}
+
+	slots, err := s.GetFreeSlots(ctx, userID, from, to, task.Duration)
+	if err != nil {
+		return nil, err
+	}
+	slot, ok := domain.PickSlot(slots, task.Duration, task.Priority)
+	if !ok {
+		return nil, fmt.Errorf("%w for %s before %s", domain.ErrNoFreeSlot, task.Duration, to.Format(time.RFC3339))
+	}
+
+	schedules := []domain.Schedule{{
+		Title:       task.Title,
+		Description: task.Description,
+		StartTime:   slot.Start,
+		EndTime:     slot.End,
+		RepeatType:  domain.RepeatNone,
+	}}
+	if err := s.prepareNew(ctx, userID, schedules); err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := s.repo.SaveMany(ctx, schedules); err != nil {
+			return nil, fmt.Errorf("failed to save schedule: %w", err)
+		}
+	}
+	return &schedules[0], nil
+}
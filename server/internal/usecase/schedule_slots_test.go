@@ -0,0 +1,40 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"murim-helper/internal/domain"
+	"murim-helper/internal/repository"
+	"murim-helper/internal/usecase"
+)
+
+func TestAutoPlaceDistantDeadline(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	uc := usecase.NewScheduleUsecase(repo, nil)
+
+	earliest := time.Now().UTC().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	saveSeries(t, repo, "standup", "Standup", earliest.Add(9*time.Hour), 15*time.Minute, "FREQ=DAILY")
+
+	// Low priority takes the latest slot, so it shows where the search ended
+	deadline := earliest.AddDate(10, 0, 0)
+	task := domain.PlacementTask{
+		Title:    "Sort photos",
+		Duration: time.Hour,
+		Earliest: earliest,
+		Deadline: &deadline,
+		Priority: domain.PriorityLow,
+	}
+	placed, err := uc.AutoPlace(ctx, domain.DefaultUserID, task, true)
+	if err != nil {
+		t.Fatalf("AutoPlace: %v", err)
+	}
+	if limit := earliest.AddDate(0, 0, 31); placed.EndTime.After(limit) {
+		t.Errorf("placed at %s, want before %s", placed.StartTime, limit)
+	}
+	if placed.StartTime.Before(earliest) {
+		t.Errorf("placed at %s, before the earliest start %s", placed.StartTime, earliest)
+	}
+}
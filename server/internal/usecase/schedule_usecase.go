@@ -3,12 +3,15 @@ package usecase
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
 	"murim-helper/internal/domain"
 	"murim-helper/internal/dto"
 	"murim-helper/internal/repository"
 	"murim-helper/internal/service"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,64 +19,245 @@ import (
 )
 
 type ScheduleUsecase interface {
-	GenerateSchedule(ctx context.Context, description string) ([]domain.Schedule, error)
-	UpdateSchedule(ctx context.Context, id string, updated domain.Schedule) error
-	GetAllSchedules(ctx context.Context, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error)
-	GetScheduleByID(ctx context.Context, id string) (*domain.Schedule, error)
-	DeleteScheduleByID(ctx context.Context, id string) error
-	MarkScheduleAsDone(ctx context.Context, id string) error
-	MarkScheduleAsUndone(ctx context.Context, id string) error
-	DeleteAll(ctx context.Context) error
-	ProcessRepeatingSchedules(ctx context.Context) error
+	GenerateSchedule(ctx context.Context, userID, description string, strict bool) (*domain.GenerationResult, error)
+	PreviewSchedule(ctx context.Context, userID, description string) (*domain.SchedulePreview, error)
+	CommitPreview(ctx context.Context, userID, token string, edits []domain.Schedule, strict bool) ([]domain.Schedule, []domain.Conflict, error)
+	CreateSchedules(ctx context.Context, userID string, schedules []domain.Schedule, strict bool) ([]domain.Schedule, []domain.Conflict, error)
+	QuickAdd(ctx context.Context, userID, text string, loc *time.Location, strict, dryRun bool) (*domain.GenerationResult, error)
+	PurgeExpiredPreviews(ctx context.Context) error
+	UpdateSchedule(ctx context.Context, userID, id string, scope domain.EditScope, updated domain.Schedule, strict bool) ([]domain.Conflict, error)
+	GetAllSchedules(ctx context.Context, userID string, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error)
+	GetRange(ctx context.Context, userID string, filter dto.ScheduleFilter, cursor string, limit int) ([]domain.Schedule, string, error)
+	GetScheduleByID(ctx context.Context, userID, id string) (*domain.Schedule, error)
+	GetConflicts(ctx context.Context, userID string, from, to time.Time) ([]domain.Conflict, error)
+	GetFreeSlots(ctx context.Context, userID string, from, to time.Time, minDuration time.Duration) ([]domain.TimeSlot, error)
+	AutoPlace(ctx context.Context, userID string, task domain.PlacementTask, dryRun bool) (*domain.Schedule, error)
+	ReplanDay(ctx context.Context, userID string, day time.Time, instruction string) (*domain.ReplanProposal, error)
+	AcceptReplan(ctx context.Context, userID, token string) (*domain.ReplanProposal, error)
+	RejectReplan(ctx context.Context, userID, token string) error
+	DeleteScheduleByID(ctx context.Context, userID, id string, scope domain.EditScope) error
+	MarkScheduleAsDone(ctx context.Context, userID, id string, scope domain.EditScope) error
+	MarkScheduleAsUndone(ctx context.Context, userID, id string, scope domain.EditScope) error
+	DeleteAll(ctx context.Context, userID string) error
+	ResolveLocation(ctx context.Context, userID, requested string) (*time.Location, error)
 }
 
+// previewTTL is how long a generated preview can be committed
+const previewTTL = 30 * time.Minute
+
 type scheduleUsecase struct {
-	repo   *repository.PostgresRepo
-	groqAI service.GroqService
+	repo repository.ScheduleRepository
+	ai   *service.GeneratorChain
+}
+
+func NewScheduleUsecase(r repository.ScheduleRepository, ai *service.GeneratorChain) ScheduleUsecase {
+	return &scheduleUsecase{repo: r, ai: ai}
 }
 
-func NewScheduleUsecase(r *repository.PostgresRepo, ai service.GroqService) ScheduleUsecase {
-	return &scheduleUsecase{repo: r, groqAI: ai}
+// GenerateSchedule generates and saves schedules, reporting the ones that
+// overlap existing schedules. With strict set nothing is saved when any do.
+func (s *scheduleUsecase) GenerateSchedule(ctx context.Context, userID, desc string, strict bool) (*domain.GenerationResult, error) {
+	result, err := s.generate(ctx, userID, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Conflicts, err = s.checkConflicts(ctx, userID, result.Schedules, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveMany(ctx, result.Schedules); err != nil {
+		return nil, fmt.Errorf("failed to save generated schedules: %w", err)
+	}
+
+	return result, nil
 }
 
-func (s *scheduleUsecase) GenerateSchedule(ctx context.Context, desc string) ([]domain.Schedule, error) {
+func (s *scheduleUsecase) PreviewSchedule(ctx context.Context, userID, desc string) (*domain.SchedulePreview, error) {
+	result, err := s.generate(ctx, userID, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts, err := s.checkConflicts(ctx, userID, result.Schedules, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preview := domain.SchedulePreview{
+		Token:     uuid.NewString(),
+		UserID:    userID,
+		Schedules: result.Schedules,
+		Provider:  result.Provider,
+		Attempts:  result.Attempts,
+		Conflicts: conflicts,
+		CreatedAt: now,
+		ExpiresAt: now.Add(previewTTL),
+	}
+	if err := s.repo.SavePreview(ctx, preview); err != nil {
+		return nil, fmt.Errorf("failed to save preview: %w", err)
+	}
+
+	return &preview, nil
+}
+
+// CommitPreview saves the previewed schedules. When edits is non-nil it
+// replaces the previewed items entirely, so the client sends back the full
+// list it wants to keep. Conflicts are checked again since the calendar may
+// have changed since the preview.
+func (s *scheduleUsecase) CommitPreview(ctx context.Context, userID, token string, edits []domain.Schedule, strict bool) ([]domain.Schedule, []domain.Conflict, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, nil, errors.New("token cannot be empty")
+	}
+
+	preview, err := s.repo.GetPreview(ctx, userID, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("preview %s not found or expired: %w", token, err)
+		}
+		return nil, nil, fmt.Errorf("failed to get preview: %w", err)
+	}
+
+	schedules := preview.Schedules
+	if edits != nil {
+		if err := s.prepareNew(ctx, userID, edits); err != nil {
+			return nil, nil, err
+		}
+		schedules = edits
+	}
+
+	conflicts, err := s.checkConflicts(ctx, userID, schedules, strict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repo.CommitPreview(ctx, userID, token, schedules); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("preview %s was already committed: %w", token, err)
+		}
+		return nil, nil, fmt.Errorf("failed to commit preview: %w", err)
+	}
+
+	return schedules, conflicts, nil
+}
+
+// CreateSchedules saves schedules entered by hand, reporting the ones that
+// overlap existing schedules. With strict set nothing is saved when any do.
+func (s *scheduleUsecase) CreateSchedules(ctx context.Context, userID string, schedules []domain.Schedule, strict bool) ([]domain.Schedule, []domain.Conflict, error) {
+	if len(schedules) == 0 {
+		return nil, nil, errors.New("no schedules to create")
+	}
+	if err := s.prepareNew(ctx, userID, schedules); err != nil {
+		return nil, nil, err
+	}
+
+	conflicts, err := s.checkConflicts(ctx, userID, schedules, strict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repo.SaveMany(ctx, schedules); err != nil {
+		return nil, nil, fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return schedules, conflicts, nil
+}
+
+// prepareNew assigns IDs and the owner to schedules that are about to be
+// created, defaulting their timezone to the user's profile
+func (s *scheduleUsecase) prepareNew(ctx context.Context, userID string, schedules []domain.Schedule) error {
+	profile, err := loadProfile(ctx, s.repo, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range schedules {
+		sc := &schedules[i]
+		sc.ID = uuid.NewString()
+		sc.SeriesID = sc.ID
+		sc.UserID = userID
+		sc.IsDone = false
+		sc.CreatedAt = now
+		if sc.Timezone == "" {
+			sc.Timezone = profile.Timezone
+		}
+		if err := sc.NormalizeRecurrence(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *scheduleUsecase) PurgeExpiredPreviews(ctx context.Context) error {
+	if _, err := s.repo.DeleteExpiredPreviews(ctx); err != nil {
+		return err
+	}
+	_, err := s.repo.DeleteExpiredReplans(ctx)
+	return err
+}
+
+// generate runs the AI chain and assigns the generated schedules to the user
+func (s *scheduleUsecase) generate(ctx context.Context, userID, desc string) (*domain.GenerationResult, error) {
 	if strings.TrimSpace(desc) == "" {
 		return nil, errors.New("description cannot be empty")
 	}
 
+	profile, err := loadProfile(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Add timeout for AI call
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	schedules, err := s.groqAI.GenerateScheduleFromText(desc)
+	result, err := s.ai.Generate(ctx, desc, *profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate schedule from text: %w", err)
 	}
 
-	if len(schedules) == 0 {
-		return nil, errors.New("AI returned no schedules")
-	}
-
-	if err := s.repo.SaveMany(ctx, schedules); err != nil {
-		return nil, fmt.Errorf("failed to save generated schedules: %w", err)
+	for i := range result.Schedules {
+		result.Schedules[i].UserID = userID
+		result.Schedules[i].Timezone = profile.Timezone
+		// RepeatUntil was derived before the timezone was known
+		if err := result.Schedules[i].NormalizeRecurrence(); err != nil {
+			return nil, err
+		}
 	}
-
-	return schedules, nil
+	return result, nil
 }
 
-func (s *scheduleUsecase) UpdateSchedule(ctx context.Context, id string, updated domain.Schedule) error {
+// UpdateSchedule updates a one-off schedule, or the occurrences of a
+// recurring one selected by scope, and reports the schedules the result
+// overlaps. With strict set nothing is saved when it overlaps any.
+func (s *scheduleUsecase) UpdateSchedule(ctx context.Context, userID, id string, scope domain.EditScope, updated domain.Schedule, strict bool) ([]domain.Conflict, error) {
 	if strings.TrimSpace(id) == "" {
-		return errors.New("id cannot be empty")
+		return nil, errors.New("id cannot be empty")
 	}
 
-	// Check if exists
-	existing, err := s.repo.GetByID(ctx, id)
+	t, err := s.resolveTarget(ctx, userID, id, scope)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("schedule with id %s not found", id)
-		}
-		return fmt.Errorf("failed to check schedule existence: %w", err)
+		return nil, err
+	}
+	switch t.scope {
+	case domain.ScopeOccurrence:
+		return s.updateOccurrence(ctx, userID, t, updated, strict)
+	case domain.ScopeFollowing:
+		return s.updateFollowing(ctx, userID, t, updated, strict)
+	case domain.ScopeSeries:
+		return s.updateSeries(ctx, userID, t, updated, strict)
 	}
+	existing := t.row
+
+	// Keep the row's identity so the conflict check sees the update as
+	// replacing it rather than overlapping it
+	updated.ID = existing.ID
+	updated.SeriesID = existing.SeriesID
+	updated.UserID = existing.UserID
+	updated.Timezone = existing.Timezone
+	updated.CreatedAt = existing.CreatedAt
 
 	// Merge fields (partial update support)
 	if updated.Title == "" {
@@ -88,128 +272,411 @@ func (s *scheduleUsecase) UpdateSchedule(ctx context.Context, id string, updated
 	if updated.EndTime.IsZero() {
 		updated.EndTime = existing.EndTime
 	}
-	if updated.RepeatType == "" {
+	if updated.RRule == "" && updated.RepeatType == "" {
+		updated.RRule = existing.RRule
 		updated.RepeatType = existing.RepeatType
 	}
-	if updated.RepeatUntil.IsZero() && !existing.RepeatUntil.IsZero() {
+	if updated.RepeatUntil == nil && existing.RepeatUntil != nil {
 		updated.RepeatUntil = existing.RepeatUntil
 	}
+	if err := updated.NormalizeRecurrence(); err != nil {
+		return nil, err
+	}
 
-	return s.repo.Update(ctx, id, updated)
+	conflicts, err := s.checkConflicts(ctx, userID, []domain.Schedule{updated}, strict)
+	if err != nil {
+		return conflicts, err
+	}
+	return conflicts, s.repo.Update(ctx, userID, id, updated)
 }
 
-func (s *scheduleUsecase) GetAllSchedules(ctx context.Context, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error) {
-	schedules, total, err := s.repo.GetAll(ctx, page, limit, filter)
+// updateOccurrence stores the changes to one occurrence as an exception of
+// its series. The recurrence itself can only be changed on the series.
+func (s *scheduleUsecase) updateOccurrence(ctx context.Context, userID string, t *editTarget, updated domain.Schedule, strict bool) ([]domain.Conflict, error) {
+	series, occ, exc, start := t.row, t.occ, t.exc, t.start
+	if exc == nil {
+		return nil, fmt.Errorf("%w: scope=occurrence needs an occurrence ID", domain.ErrInvalidScope)
+	}
+
+	if updated.Title == "" {
+		updated.Title = occ.Title
+	}
+	if updated.Description == "" {
+		updated.Description = occ.Description
+	}
+	if updated.StartTime.IsZero() {
+		updated.StartTime = occ.StartTime
+	}
+	if updated.EndTime.IsZero() {
+		updated.EndTime = occ.EndTime
+	}
+	if updated.RRule != "" || updated.RepeatType != "" {
+		if err := updated.NormalizeRecurrence(); err != nil {
+			return nil, err
+		}
+		if updated.RRule != series.RRule {
+			return nil, fmt.Errorf("%w: the recurrence can only be changed on the whole series", domain.ErrInvalidRecurrence)
+		}
+	}
+
+	// The occurrence is checked on its own, not as the series it belongs to
+	single := updated
+	single.ID = domain.OccurrenceID(series.ID, start)
+	single.RRule = ""
+	conflicts, err := s.checkConflicts(ctx, userID, []domain.Schedule{single}, strict)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get schedules: %w", err)
+		return conflicts, err
 	}
-	return schedules, total, nil
+
+	exc.SetOverrides(series.Occurrence(start, nil), updated)
+	return conflicts, s.repo.SaveException(ctx, *exc)
 }
 
-func (s *scheduleUsecase) GetScheduleByID(ctx context.Context, id string) (*domain.Schedule, error) {
-	if strings.TrimSpace(id) == "" {
-		return nil, errors.New("id cannot be empty")
+// getOccurrence loads an occurrence of a series together with its exception,
+// which is new when the occurrence has none yet. A cancelled occurrence or a
+// start the rule does not generate is reported as sql.ErrNoRows.
+func (s *scheduleUsecase) getOccurrence(ctx context.Context, userID, seriesID string, start time.Time) (*domain.Schedule, *domain.Schedule, *domain.ScheduleException, error) {
+	series, err := s.repo.GetByID(ctx, userID, seriesID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !series.IsOccurrence(start) {
+		return nil, nil, nil, sql.ErrNoRows
 	}
-	return s.repo.GetByID(ctx, id)
+
+	exc, err := s.repo.GetException(ctx, seriesID, start)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil, err
+		}
+		exc = &domain.ScheduleException{ScheduleID: seriesID, OccurrenceStart: start}
+	}
+	if exc.Cancelled {
+		return nil, nil, nil, sql.ErrNoRows
+	}
+
+	occ := series.Occurrence(start, exc)
+	return series, &occ, exc, nil
 }
 
-func (s *scheduleUsecase) DeleteScheduleByID(ctx context.Context, id string) error {
-	if strings.TrimSpace(id) == "" {
-		return errors.New("id cannot be empty")
+// GetAllSchedules lists schedules page by page. When the filter has both a
+// start_after and a start_before bound, recurring series are expanded into
+// their occurrences in that window, which may be at most
+// dto.MaxCalendarRange long and needs a limit; otherwise each series is
+// listed once.
+func (s *scheduleUsecase) GetAllSchedules(ctx context.Context, userID string, page, limit int, filter dto.ScheduleFilter) ([]domain.Schedule, int, error) {
+	if filter.StartAfter == nil || filter.StartBefore == nil {
+		schedules, total, err := s.repo.GetAll(ctx, userID, page, limit, filter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get schedules: %w", err)
+		}
+		return schedules, total, nil
 	}
 
-	// Check existence
-	_, err := s.repo.GetByID(ctx, id)
+	if filter.StartBefore.Sub(*filter.StartAfter) > dto.MaxCalendarRange {
+		return nil, 0, fmt.Errorf("%w: start_after and start_before cannot be more than 366 days apart", domain.ErrRangeTooLarge)
+	}
+	if limit <= 0 {
+		return nil, 0, fmt.Errorf("%w: a limit is required when start_after and start_before are set", domain.ErrRangeTooLarge)
+	}
+
+	schedules, err := s.listInRange(ctx, userID, filter)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("schedule with id %s not found", id)
+		return nil, 0, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	total := len(schedules)
+	from := (page - 1) * limit
+	if from > total {
+		from = total
+	}
+	to := from + limit
+	if to > total {
+		to = total
+	}
+	return schedules[from:to], total, nil
+}
+
+// rangeStep is the first window GetRange reads for a page. It doubles until
+// the window holds more than a page or reaches the end of the range.
+const rangeStep = 7 * 24 * time.Hour
+
+// GetRange lists the one-off schedules and recurring occurrences starting in
+// the filter's window, ordered by start time. A page continues after cursor,
+// and the returned cursor is empty on the last page. A limit of 0 returns
+// the rest of the window.
+func (s *scheduleUsecase) GetRange(ctx context.Context, userID string, filter dto.ScheduleFilter, cursor string, limit int) ([]domain.Schedule, string, error) {
+	if filter.StartAfter == nil || filter.StartBefore == nil {
+		return nil, "", errors.New("range needs a start and an end")
+	}
+
+	var after *rangeCursor
+	if cursor != "" {
+		c, err := decodeRangeCursor(cursor)
+		if err != nil {
+			return nil, "", err
 		}
-		return fmt.Errorf("failed to check schedule existence: %w", err)
+		after = &c
 	}
 
-	return s.repo.DeleteByID(ctx, id)
+	// Only what starts at or after the cursor is read, in a window that
+	// grows until it holds more than a page, so a page costs about as much
+	// as its own items rather than the whole range
+	from, to := *filter.StartAfter, *filter.StartBefore
+	if after != nil && after.start.After(from) {
+		from = after.start
+	}
+	for span := rangeStep; ; span *= 2 {
+		end := to
+		if limit > 0 && from.Add(span).Before(to) {
+			end = from.Add(span)
+		}
+		window := filter
+		window.StartAfter, window.StartBefore = &from, &end
+		schedules, err := s.listInRange(ctx, userID, window)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get schedules: %w", err)
+		}
+		sort.SliceStable(schedules, func(i, j int) bool {
+			return cursorOf(schedules[i]).before(cursorOf(schedules[j]))
+		})
+
+		if after != nil {
+			i := sort.Search(len(schedules), func(i int) bool {
+				return after.before(cursorOf(schedules[i]))
+			})
+			schedules = schedules[i:]
+		}
+		if limit > 0 && len(schedules) > limit {
+			schedules = schedules[:limit]
+			return schedules, cursorOf(schedules[limit-1]).encode(), nil
+		}
+		if !end.Before(to) {
+			return schedules, "", nil
+		}
+	}
 }
 
-func (s *scheduleUsecase) MarkScheduleAsDone(ctx context.Context, id string) error {
-	return s.setDoneStatus(ctx, id, true)
+// rangeCursor is the position of an item in a range listing
+type rangeCursor struct {
+	start time.Time
+	id    string
 }
 
-func (s *scheduleUsecase) MarkScheduleAsUndone(ctx context.Context, id string) error {
-	return s.setDoneStatus(ctx, id, false)
+func cursorOf(s domain.Schedule) rangeCursor {
+	return rangeCursor{start: s.StartTime, id: s.ID}
 }
 
-func (s *scheduleUsecase) setDoneStatus(ctx context.Context, id string, done bool) error {
-	if strings.TrimSpace(id) == "" {
-		return errors.New("id cannot be empty")
+func (c rangeCursor) before(other rangeCursor) bool {
+	if !c.start.Equal(other.start) {
+		return c.start.Before(other.start)
 	}
+	return c.id < other.id
+}
 
-	schedule, err := s.repo.GetByID(ctx, id)
+func (c rangeCursor) encode() string {
+	raw := c.start.UTC().Format(time.RFC3339Nano) + "|" + c.id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeRangeCursor(cursor string) (rangeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("schedule with id %s not found", id)
-		}
-		return fmt.Errorf("failed to get schedule by ID: %w", err)
+		return rangeCursor{}, domain.ErrInvalidCursor
 	}
-
-	schedule.IsDone = done
-	return s.repo.Update(ctx, id, *schedule)
+	start, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return rangeCursor{}, domain.ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		return rangeCursor{}, domain.ErrInvalidCursor
+	}
+	return rangeCursor{start: t, id: id}, nil
 }
 
-func (s *scheduleUsecase) DeleteAll(ctx context.Context) error {
-	return s.repo.DeleteAll(ctx)
+// listInRange returns the one-off schedules and the occurrences of recurring
+// series that match filter, sorted as requested
+func (s *scheduleUsecase) listInRange(ctx context.Context, userID string, filter dto.ScheduleFilter) ([]domain.Schedule, error) {
+	from, to := *filter.StartAfter, *filter.StartBefore
+
+	schedules, err := s.repo.GetOneOffs(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := s.repo.GetSeriesInRange(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	occurrences, err := s.expandSeries(ctx, series, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, occ := range occurrences {
+		if matchesFilter(occ, filter) {
+			schedules = append(schedules, occ)
+		}
+	}
+
+	sortSchedules(schedules, filter.SortBy, filter.SortOrder)
+	return schedules, nil
 }
 
-func (s *scheduleUsecase) ProcessRepeatingSchedules(ctx context.Context) error {
-	schedules, err := s.repo.GetRepeatingSchedules(ctx)
+// expandSeries expands every series into its occurrences in [from, to)
+func (s *scheduleUsecase) expandSeries(ctx context.Context, series []domain.Schedule, from, to time.Time) ([]domain.Schedule, error) {
+	ids := make([]string, len(series))
+	for i, sr := range series {
+		ids[i] = sr.ID
+	}
+	exceptions, err := s.repo.GetExceptions(ctx, ids, from, to)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	bySeries := make(map[string][]domain.ScheduleException)
+	for _, exc := range exceptions {
+		bySeries[exc.ScheduleID] = append(bySeries[exc.ScheduleID], exc)
 	}
 
-	for _, sched := range schedules {
-		nextStart, nextEnd := getNextOccurrence(sched)
-		if nextStart == nil || nextEnd == nil {
+	var occurrences []domain.Schedule
+	for _, sr := range series {
+		occ, err := sr.Occurrences(from, to, bySeries[sr.ID])
+		if err != nil {
+			// One broken rule must not hide the rest of the calendar
+			log.Printf("[Recurrence] skipping schedule %s: %v", sr.ID, err)
 			continue
 		}
+		occurrences = append(occurrences, occ...)
+	}
+	return occurrences, nil
+}
 
-		// Skip if next occurrence already exists
-		exists, err := s.repo.ExistsByStartTime(ctx, sched.Title, *nextStart)
-		if err != nil {
-			return err
+// matchesFilter applies the non-window filters to an expanded occurrence
+func matchesFilter(s domain.Schedule, filter dto.ScheduleFilter) bool {
+	if filter.IsDone != nil && s.IsDone != *filter.IsDone {
+		return false
+	}
+	if filter.RepeatType != "" && s.RepeatType != filter.RepeatType {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(s.Title), search) && !strings.Contains(strings.ToLower(s.Description), search) {
+			return false
 		}
-		if exists {
-			continue
+	}
+	return true
+}
+
+// sortSchedules sorts in memory using the same columns GetAll allows
+func sortSchedules(schedules []domain.Schedule, sortBy, order string) {
+	less := func(a, b domain.Schedule) bool { return a.StartTime.Before(b.StartTime) }
+	switch sortBy {
+	case "end_time":
+		less = func(a, b domain.Schedule) bool { return a.EndTime.Before(b.EndTime) }
+	case "created_at":
+		less = func(a, b domain.Schedule) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "title":
+		less = func(a, b domain.Schedule) bool { return a.Title < b.Title }
+	}
+	desc := strings.ToLower(order) == "desc"
+	sort.SliceStable(schedules, func(i, j int) bool {
+		if desc {
+			return less(schedules[j], schedules[i])
+		}
+		return less(schedules[i], schedules[j])
+	})
+}
+
+func (s *scheduleUsecase) GetScheduleByID(ctx context.Context, userID, id string) (*domain.Schedule, error) {
+	if strings.TrimSpace(id) == "" {
+		return nil, errors.New("id cannot be empty")
+	}
+	if seriesID, start, ok := domain.ParseOccurrenceID(id); ok {
+		_, occ, _, err := s.getOccurrence(ctx, userID, seriesID, start)
+		return occ, err
+	}
+	return s.repo.GetByID(ctx, userID, id)
+}
+
+// DeleteScheduleByID deletes a one-off schedule, or the occurrences of a
+// recurring one selected by scope. A single occurrence is cancelled.
+func (s *scheduleUsecase) DeleteScheduleByID(ctx context.Context, userID, id string, scope domain.EditScope) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("id cannot be empty")
+	}
+
+	t, err := s.resolveTarget(ctx, userID, id, scope)
+	if err != nil {
+		return err
+	}
+	switch t.scope {
+	case domain.ScopeOccurrence:
+		if t.exc == nil {
+			return fmt.Errorf("%w: scope=occurrence needs an occurrence ID", domain.ErrInvalidScope)
 		}
+		t.exc.Cancelled = true
+		return s.repo.SaveException(ctx, *t.exc)
+	case domain.ScopeFollowing:
+		return s.deleteFollowing(ctx, userID, t)
+	case domain.ScopeSeries:
+		return s.deleteSeries(ctx, userID, t)
+	}
+
+	return s.repo.DeleteByID(ctx, userID, id)
+}
+
+func (s *scheduleUsecase) MarkScheduleAsDone(ctx context.Context, userID, id string, scope domain.EditScope) error {
+	return s.setDoneStatus(ctx, userID, id, scope, true)
+}
+
+func (s *scheduleUsecase) MarkScheduleAsUndone(ctx context.Context, userID, id string, scope domain.EditScope) error {
+	return s.setDoneStatus(ctx, userID, id, scope, false)
+}
 
-		// Create new schedule
-		newSched := sched
-		newSched.ID = uuid.New().String()
-		newSched.StartTime = *nextStart
-		newSched.EndTime = *nextEnd
-		newSched.CreatedAt = time.Now()
-		newSched.IsDone = false
+func (s *scheduleUsecase) setDoneStatus(ctx context.Context, userID, id string, scope domain.EditScope, done bool) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("id cannot be empty")
+	}
 
-		if err := s.repo.SaveMany(ctx, []domain.Schedule{newSched}); err != nil {
-			return fmt.Errorf("failed to save next occurrence: %w", err)
+	t, err := s.resolveTarget(ctx, userID, id, scope)
+	if err != nil {
+		return err
+	}
+	switch t.scope {
+	case domain.ScopeOccurrence:
+		if t.exc == nil {
+			return fmt.Errorf("%w: scope=occurrence needs an occurrence ID", domain.ErrInvalidScope)
 		}
+		t.exc.IsDone = done
+		return s.repo.SaveException(ctx, *t.exc)
+	case domain.ScopeFollowing, domain.ScopeSeries:
+		return s.setDoneFrom(ctx, userID, t, done)
 	}
-	return nil
+
+	schedule := t.row
+	schedule.IsDone = done
+	return s.repo.Update(ctx, userID, id, *schedule)
+}
+
+func (s *scheduleUsecase) DeleteAll(ctx context.Context, userID string) error {
+	return s.repo.DeleteAll(ctx, userID)
 }
 
-func getNextOccurrence(sched domain.Schedule) (*time.Time, *time.Time) {
-	var nextStart, nextEnd time.Time
-	switch sched.RepeatType {
-	case "daily":
-		nextStart = sched.StartTime.Add(24 * time.Hour)
-		nextEnd = sched.EndTime.Add(24 * time.Hour)
-	case "weekly":
-		nextStart = sched.StartTime.AddDate(0, 0, 7)
-		nextEnd = sched.EndTime.AddDate(0, 0, 7)
-	default:
-		return nil, nil
+// ResolveLocation returns the timezone a request is answered in: the
+// requested IANA name when one is given, otherwise the user's profile timezone
+func (s *scheduleUsecase) ResolveLocation(ctx context.Context, userID, requested string) (*time.Location, error) {
+	if requested = strings.TrimSpace(requested); requested != "" {
+		loc, err := domain.LoadLocation(requested)
+		if err != nil || requested == "Local" {
+			return nil, fmt.Errorf("%w %q", domain.ErrInvalidTimezone, requested)
+		}
+		return loc, nil
 	}
 
-	if sched.RepeatUntil != nil && nextStart.After(*sched.RepeatUntil) {
-		return nil, nil
+	profile, err := loadProfile(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
 	}
-	return &nextStart, &nextEnd
+	return profile.Location(), nil
 }
@@ -0,0 +1,236 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"murim-helper/internal/domain"
+	"murim-helper/internal/dto"
+	"murim-helper/internal/repository"
+	"murim-helper/internal/usecase"
+)
+
+func saveOneOff(t *testing.T, repo repository.ScheduleRepository, id, title string, start time.Time, d time.Duration) {
+	t.Helper()
+	s := domain.Schedule{
+		ID:          id,
+		SeriesID:    id,
+		UserID:      domain.DefaultUserID,
+		Title:       title,
+		Description: title + " notes",
+		StartTime:   start,
+		EndTime:     start.Add(d),
+		Timezone:    "UTC",
+		CreatedAt:   start.Add(-24 * time.Hour),
+	}
+	if err := repo.SaveMany(context.Background(), []domain.Schedule{s}); err != nil {
+		t.Fatalf("SaveMany: %v", err)
+	}
+}
+
+func TestUpdateOneOffSchedule(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	uc := usecase.NewScheduleUsecase(repo, nil)
+
+	start := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+	saveOneOff(t, repo, "a", "Write report", start, time.Hour)
+	saveOneOff(t, repo, "b", "Gym", start.Add(2*time.Hour), time.Hour)
+
+	// A partial update without repeat_until keeps the other fields and does
+	// not conflict with the row it replaces
+	conflicts, err := uc.UpdateSchedule(ctx, domain.DefaultUserID, "a", "", domain.Schedule{Title: "Write final report"}, true)
+	if err != nil {
+		t.Fatalf("UpdateSchedule: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("conflicts = %+v, want none", conflicts)
+	}
+
+	got, err := repo.GetByID(ctx, domain.DefaultUserID, "a")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Title != "Write final report" || got.Description != "Write report notes" {
+		t.Errorf("title, description = %q, %q", got.Title, got.Description)
+	}
+	if !got.StartTime.Equal(start) || !got.EndTime.Equal(start.Add(time.Hour)) {
+		t.Errorf("times = %v - %v, want %v - %v", got.StartTime, got.EndTime, start, start.Add(time.Hour))
+	}
+	if got.RRule != "" || got.RepeatUntil != nil {
+		t.Errorf("recurrence = %q until %v, want none", got.RRule, got.RepeatUntil)
+	}
+
+	// Moving it onto the other schedule is reported, and refused when strict
+	moved := domain.Schedule{StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour)}
+	if _, err := uc.UpdateSchedule(ctx, domain.DefaultUserID, "a", "", moved, true); err == nil {
+		t.Fatal("strict update onto an existing schedule succeeded")
+	}
+	conflicts, err = uc.UpdateSchedule(ctx, domain.DefaultUserID, "a", "", moved, false)
+	if err != nil {
+		t.Fatalf("UpdateSchedule: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].ScheduleID != "a" || conflicts[0].ConflictingID != "b" {
+		t.Errorf("conflicts = %+v, want a overlapping b", conflicts)
+	}
+}
+
+func saveSeries(t *testing.T, repo repository.ScheduleRepository, id, title string, start time.Time, d time.Duration, rule string) {
+	t.Helper()
+	s := domain.Schedule{
+		ID:        id,
+		SeriesID:  id,
+		UserID:    domain.DefaultUserID,
+		Title:     title,
+		StartTime: start,
+		EndTime:   start.Add(d),
+		RRule:     rule,
+		Timezone:  "UTC",
+		CreatedAt: start,
+	}
+	if err := s.NormalizeRecurrence(); err != nil {
+		t.Fatalf("NormalizeRecurrence: %v", err)
+	}
+	if err := repo.SaveMany(context.Background(), []domain.Schedule{s}); err != nil {
+		t.Fatalf("SaveMany: %v", err)
+	}
+}
+
+func TestMarkOldSeriesDone(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	uc := usecase.NewScheduleUsecase(repo, nil)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	start := today.AddDate(-2, 0, 0).Add(9 * time.Hour)
+	saveSeries(t, repo, "standup", "Standup", start, 15*time.Minute, "FREQ=DAILY")
+
+	// Two years of occurrences would each need an exception
+	err := uc.MarkScheduleAsDone(ctx, domain.DefaultUserID, "standup", domain.ScopeSeries)
+	if !errors.Is(err, domain.ErrRangeTooLarge) {
+		t.Fatalf("marking a two year old series done: got %v, want ErrRangeTooLarge", err)
+	}
+	excs, _ := repo.GetExceptions(ctx, []string{"standup"}, start, today.AddDate(0, 0, 1))
+	if len(excs) != 0 {
+		t.Fatalf("refused request saved %d exceptions", len(excs))
+	}
+
+	recent := today.AddDate(0, 0, -3).Add(9 * time.Hour)
+	id := domain.OccurrenceID("standup", recent)
+	if err := uc.MarkScheduleAsDone(ctx, domain.DefaultUserID, id, domain.ScopeFollowing); err != nil {
+		t.Fatalf("MarkScheduleAsDone from a recent occurrence: %v", err)
+	}
+	excs, _ = repo.GetExceptions(ctx, []string{"standup"}, start, today.AddDate(0, 0, 1))
+	if len(excs) < 3 {
+		t.Fatalf("exceptions = %d, want at least the three days before today", len(excs))
+	}
+	for _, exc := range excs {
+		if !exc.IsDone || exc.OccurrenceStart.Before(recent) {
+			t.Errorf("unexpected exception %+v", exc)
+		}
+	}
+}
+
+func TestGetAllSchedulesBoundsExpansion(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	uc := usecase.NewScheduleUsecase(repo, nil)
+
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	saveSeries(t, repo, "standup", "Standup", start, 15*time.Minute, "FREQ=DAILY")
+
+	from, to := start, start.AddDate(100, 0, 0)
+	_, _, err := uc.GetAllSchedules(ctx, domain.DefaultUserID, 1, 10, dto.ScheduleFilter{StartAfter: &from, StartBefore: &to})
+	if !errors.Is(err, domain.ErrRangeTooLarge) {
+		t.Errorf("100 year window: got %v, want ErrRangeTooLarge", err)
+	}
+
+	to = start.AddDate(0, 0, 7)
+	filter := dto.ScheduleFilter{StartAfter: &from, StartBefore: &to}
+	if _, _, err := uc.GetAllSchedules(ctx, domain.DefaultUserID, 1, 0, filter); !errors.Is(err, domain.ErrRangeTooLarge) {
+		t.Errorf("limit 0: got %v, want ErrRangeTooLarge", err)
+	}
+	page, total, err := uc.GetAllSchedules(ctx, domain.DefaultUserID, 2, 5, filter)
+	if err != nil {
+		t.Fatalf("GetAllSchedules: %v", err)
+	}
+	if total != 7 || len(page) != 2 {
+		t.Errorf("second page: got %d of %d, want 2 of 7", len(page), total)
+	}
+}
+
+// windowRepo records the windows one-offs are read over
+type windowRepo struct {
+	*repository.MemoryRepo
+	windows [][2]time.Time
+}
+
+func (r *windowRepo) GetOneOffs(ctx context.Context, userID string, filter dto.ScheduleFilter) ([]domain.Schedule, error) {
+	r.windows = append(r.windows, [2]time.Time{*filter.StartAfter, *filter.StartBefore})
+	return r.MemoryRepo.GetOneOffs(ctx, userID, filter)
+}
+
+func TestGetRangePages(t *testing.T) {
+	ctx := context.Background()
+	repo := &windowRepo{MemoryRepo: repository.NewMemoryRepo()}
+	uc := usecase.NewScheduleUsecase(repo, nil)
+
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	saveSeries(t, repo, "standup", "Standup", start, 15*time.Minute, "FREQ=DAILY")
+	saveSeries(t, repo, "review", "Review", start.Add(8*time.Hour), time.Hour, "FREQ=WEEKLY")
+	// Starts together with an occurrence, so the cursor has to break the tie
+	saveOneOff(t, repo, "call", "Call", start.AddDate(0, 0, 3), time.Hour)
+	saveOneOff(t, repo, "dentist", "Dentist", start.AddDate(0, 2, 0).Add(5*time.Hour), time.Hour)
+
+	from, to := start, start.AddDate(0, 3, 0)
+	filter := dto.ScheduleFilter{StartAfter: &from, StartBefore: &to}
+	all, next, err := uc.GetRange(ctx, domain.DefaultUserID, filter, "", 0)
+	if err != nil || next != "" {
+		t.Fatalf("whole range: %v, cursor %q", err, next)
+	}
+	if len(all) != 90+13+2 {
+		t.Fatalf("whole range: got %d items, want %d", len(all), 90+13+2)
+	}
+
+	var paged []domain.Schedule
+	cursor, pages := "", 0
+	for {
+		repo.windows = nil
+		page, next, err := uc.GetRange(ctx, domain.DefaultUserID, filter, cursor, 7)
+		if err != nil {
+			t.Fatalf("page %d: %v", pages, err)
+		}
+		if cursor != "" {
+			// Later pages only read from the last item on
+			last := paged[len(paged)-1].StartTime
+			for _, w := range repo.windows {
+				if !w[0].Equal(last) {
+					t.Fatalf("page %d read from %s, want the cursor at %s", pages, w[0], last)
+				}
+			}
+		}
+		paged = append(paged, page...)
+		pages++
+		if next == "" {
+			break
+		}
+		if len(page) != 7 {
+			t.Fatalf("page %d has %d items, want 7", pages, len(page))
+		}
+		cursor = next
+	}
+
+	if pages != (len(all)+6)/7 {
+		t.Errorf("pages = %d, want %d", pages, (len(all)+6)/7)
+	}
+	if len(paged) != len(all) {
+		t.Fatalf("paged through %d items, want %d", len(paged), len(all))
+	}
+	for i := range all {
+		if paged[i].ID != all[i].ID {
+			t.Fatalf("item %d: paged %s, want %s", i, paged[i].ID, all[i].ID)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"murim-helper/internal/domain"
+	"murim-helper/internal/repository"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserUsecase interface {
+	CreateUser(ctx context.Context, name, email string) (*domain.User, error)
+	GetUser(ctx context.Context, id string) (*domain.User, error)
+}
+
+type userUsecase struct {
+	repo repository.UserRepository
+}
+
+func NewUserUsecase(r repository.UserRepository) UserUsecase {
+	return &userUsecase{repo: r}
+}
+
+func (u *userUsecase) CreateUser(ctx context.Context, name, email string) (*domain.User, error) {
+	name = strings.TrimSpace(name)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, fmt.Errorf("invalid email %q", email)
+	}
+
+	user := domain.User{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Email:     email,
+		CreatedAt: time.Now(),
+	}
+	if err := u.repo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (u *userUsecase) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	if strings.TrimSpace(id) == "" {
+		return nil, errors.New("id cannot be empty")
+	}
+	return u.repo.GetUserByID(ctx, id)
+}
@@ -26,6 +26,20 @@ func Success(w http.ResponseWriter, r *http.Request, statusCode int, message str
 	writeJSON(w, statusCode, resp)
 }
 
+// SuccessWithMeta returns a successful response carrying extra metadata
+func SuccessWithMeta(w http.ResponseWriter, r *http.Request, statusCode int, message string, payload interface{}, meta interface{}) {
+	resp := domain.ApiResponse{
+		Status:    "success",
+		Message:   message,
+		Payload:   payload,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Path:      r.URL.Path,
+		Meta:      meta,
+	}
+
+	writeJSON(w, statusCode, resp)
+}
+
 // Error returns a well-structured error response
 func Error(w http.ResponseWriter, r *http.Request, statusCode int, message string, code int) {
 	resp := domain.ApiResponse{
@@ -39,6 +53,20 @@ func Error(w http.ResponseWriter, r *http.Request, statusCode int, message strin
 	writeJSON(w, statusCode, resp)
 }
 
+// ErrorWithMeta returns an error response carrying details about the failure
+func ErrorWithMeta(w http.ResponseWriter, r *http.Request, statusCode int, message string, code int, meta interface{}) {
+	resp := domain.ApiResponse{
+		Status:    "error",
+		Message:   message,
+		Code:      code,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Path:      r.URL.Path,
+		Meta:      meta,
+	}
+
+	writeJSON(w, statusCode, resp)
+}
+
 // Helper to write JSON response
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,759 @@
+// Package quickadd reads one-line event descriptions such as
+// "lunch with Budi tomorrow 12:30 for 1h every Friday" into a title, a
+// start and end time and an optional RFC 5545 recurrence rule.
+//
+// It only understands common English phrasings of dates ("today",
+// "next monday", "aug 5", "2025-08-05"), times ("12:30", "3pm", "9-10am",
+// "from 9 to 10:30"), durations ("for 1h", "90 min", "half an hour") and
+// recurrences ("daily", "every 2 weeks", "every mon and wed", "until dec 31",
+// "10 times"). Anything it cannot account for is reported as
+// ErrUnrecognized so callers can fall back to a smarter parser.
+package quickadd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"murim-helper/pkg/rrule"
+)
+
+var ErrUnrecognized = errors.New("text not recognized")
+
+// DefaultDuration is used when the text gives a start time but neither an
+// end time nor a duration
+const DefaultDuration = time.Hour
+
+// Item is a parsed event
+type Item struct {
+	Title string
+	Start time.Time
+	End   time.Time
+	RRule string // empty for a one-off event
+}
+
+var (
+	clockPattern      = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?(am|pm)?$`)
+	durationPattern   = regexp.MustCompile(`^(?:(\d+(?:\.\d+)?)(?:h|hr|hrs|hour|hours))?(?:(\d+)(?:m|min|mins|minute|minutes))?$`)
+	isoDatePattern    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	dayOfMonthPattern = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	yearPattern       = regexp.MustCompile(`^\d{4}$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var byDayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var (
+	hourUnits   = map[string]bool{"h": true, "hr": true, "hrs": true, "hour": true, "hours": true}
+	minuteUnits = map[string]bool{"m": true, "min": true, "mins": true, "minute": true, "minutes": true}
+)
+
+// prefixes are words that introduce a date, time or duration and belong to
+// it rather than to the title, as in "at 3pm" or "on friday"
+var prefixes = map[string]bool{"at": true, "@": true, "on": true, "from": true, "for": true, "between": true}
+
+// fillers are trimmed from both ends of the title
+var fillers = map[string]bool{
+	"at": true, "@": true, "on": true, "for": true, "from": true, "to": true, "and": true,
+	"in": true, "the": true, "by": true, "between": true, "until": true, "till": true,
+	"every": true, "-": true, "&": true,
+}
+
+// dateKind records how the date was given, which decides whether a time
+// that already passed moves the event forward
+type dateKind int
+
+const (
+	dateNone     dateKind = iota // no date, today or tomorrow depending on the time
+	dateExplicit                 // "today", "next friday", "aug 5"
+	dateWeekday                  // "friday", this week's or next week's
+)
+
+type clock struct {
+	hour, minute int
+}
+
+// clockText is a time of day as written, before am/pm is applied
+type clockText struct {
+	hour, minute int
+	meridiem     string // "am", "pm" or empty
+	explicit     bool   // has minutes, am/pm or is a word like "noon"
+}
+
+func (c clockText) resolve() (clock, bool) {
+	if c.minute > 59 {
+		return clock{}, false
+	}
+	switch c.meridiem {
+	case "am":
+		if c.hour < 1 || c.hour > 12 {
+			return clock{}, false
+		}
+		return clock{c.hour % 12, c.minute}, true
+	case "pm":
+		if c.hour < 1 || c.hour > 12 {
+			return clock{}, false
+		}
+		return clock{c.hour%12 + 12, c.minute}, true
+	}
+	if c.hour > 23 {
+		return clock{}, false
+	}
+	return clock{c.hour, c.minute}, true
+}
+
+type token struct {
+	text  string // as typed, without surrounding punctuation
+	lower string
+	used  bool
+}
+
+type parser struct {
+	tokens []token
+	now    time.Time
+
+	date     *time.Time
+	kind     dateKind
+	start    *clock
+	end      *clock
+	duration time.Duration
+
+	freq     rrule.Frequency
+	interval int
+	byDay    []string
+	count    int
+	until    *time.Time
+
+	// repeated is set when a detail is given twice, e.g. two dates
+	repeated bool
+}
+
+// Parse reads text as an event relative to now, whose location the dates
+// and times are taken in. A time that already passed today moves the event
+// to tomorrow unless a date was given. Recurring events start on their
+// first occurrence on or after the given date.
+func Parse(text string, now time.Time) (*Item, error) {
+	p := &parser{now: now}
+	for _, field := range strings.Fields(text) {
+		t := strings.Trim(field, ",;!?()\"'")
+		t = strings.TrimSuffix(t, ".")
+		if t == "" {
+			continue
+		}
+		p.tokens = append(p.tokens, token{text: t, lower: strings.ToLower(t)})
+	}
+
+	for i := 0; i < len(p.tokens); {
+		if n := p.matchAt(i); n > 0 {
+			i += n
+			continue
+		}
+		i++
+	}
+	return p.build()
+}
+
+func (p *parser) word(i int) string {
+	if i < 0 || i >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[i].lower
+}
+
+// matchAt consumes the detail starting at token i, together with a
+// prefix like "at" in front of it
+func (p *parser) matchAt(i int) int {
+	if w := p.word(i); prefixes[w] {
+		if n := p.match(i+1, w); n > 0 {
+			p.tokens[i].used = true
+			return 1 + n
+		}
+	}
+	return p.match(i, "")
+}
+
+func (p *parser) match(i int, prefix string) int {
+	if i >= len(p.tokens) {
+		return 0
+	}
+	matchers := []func(int, string) int{
+		p.matchRecurrence, p.matchUntil, p.matchCount, p.matchDate, p.matchTime, p.matchDuration,
+	}
+	for _, m := range matchers {
+		if n := m(i, prefix); n > 0 {
+			for k := i; k < i+n; k++ {
+				p.tokens[k].used = true
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func (p *parser) matchRecurrence(i int, _ string) int {
+	switch w := p.word(i); w {
+	case "daily":
+		return p.setRule(rrule.Daily, 1, nil, 1)
+	case "weekly":
+		return p.setRule(rrule.Weekly, 1, nil, 1)
+	case "monthly":
+		return p.setRule(rrule.Monthly, 1, nil, 1)
+	case "yearly", "annually":
+		return p.setRule(rrule.Yearly, 1, nil, 1)
+	case "weekdays":
+		return p.setRule(rrule.Weekly, 1, []string{"MO", "TU", "WE", "TH", "FR"}, 1)
+	case "weekends":
+		return p.setRule(rrule.Weekly, 1, []string{"SA", "SU"}, 1)
+	case "every":
+		if n := p.matchEvery(i + 1); n > 0 {
+			return 1 + n
+		}
+		return 0
+	default:
+		// "mondays and thursdays"
+		if _, plural, ok := weekdayOf(w); ok && plural {
+			days, n := p.weekdayList(i)
+			return p.setRule(rrule.Weekly, 1, days, n)
+		}
+		return 0
+	}
+}
+
+// matchEvery reads what follows "every": a unit with an optional interval
+// ("day", "2 weeks", "other month") or a list of weekdays
+func (p *parser) matchEvery(j int) int {
+	interval, n := 1, 0
+	if w := p.word(j); w == "other" {
+		interval, n = 2, 1
+	} else if v, err := strconv.Atoi(w); err == nil && v > 0 {
+		interval, n = v, 1
+	}
+
+	switch strings.TrimSuffix(p.word(j+n), "s") {
+	case "day":
+		return p.setRule(rrule.Daily, interval, nil, n+1)
+	case "week":
+		return p.setRule(rrule.Weekly, interval, nil, n+1)
+	case "month":
+		return p.setRule(rrule.Monthly, interval, nil, n+1)
+	case "year":
+		return p.setRule(rrule.Yearly, interval, nil, n+1)
+	}
+	if n > 0 {
+		return 0
+	}
+	switch p.word(j) {
+	case "weekday", "weekdays":
+		return p.setRule(rrule.Weekly, 1, []string{"MO", "TU", "WE", "TH", "FR"}, 1)
+	case "weekend", "weekends":
+		return p.setRule(rrule.Weekly, 1, []string{"SA", "SU"}, 1)
+	}
+	if days, m := p.weekdayList(j); m > 0 {
+		return p.setRule(rrule.Weekly, 1, days, m)
+	}
+	return 0
+}
+
+// weekdayList reads weekdays separated by "and", "or", "&" or nothing
+func (p *parser) weekdayList(j int) ([]string, int) {
+	var days []string
+	n := 0
+	for {
+		wd, _, ok := weekdayOf(p.word(j + n))
+		if !ok {
+			break
+		}
+		days = append(days, byDayCodes[wd])
+		n++
+		switch p.word(j + n) {
+		case "and", "or", "&":
+			if _, _, ok := weekdayOf(p.word(j + n + 1)); ok {
+				n++
+			}
+		}
+	}
+	return days, n
+}
+
+func (p *parser) setRule(freq rrule.Frequency, interval int, byDay []string, n int) int {
+	if p.freq != "" {
+		p.repeated = true
+	}
+	p.freq, p.interval, p.byDay = freq, interval, byDay
+	return n
+}
+
+// matchUntil reads "until <date>", which ends a recurrence, or
+// "until <time>", which ends the event
+func (p *parser) matchUntil(i int, _ string) int {
+	switch p.word(i) {
+	case "until", "till", "through":
+	default:
+		return 0
+	}
+	if d, _, n := p.dateAt(i + 1); n > 0 {
+		if p.until != nil {
+			p.repeated = true
+		}
+		p.until = &d
+		return 1 + n
+	}
+	if c, n := p.clockAt(i + 1); n > 0 {
+		end, ok := c.resolve()
+		if !ok {
+			return 0
+		}
+		if p.end != nil {
+			p.repeated = true
+		}
+		p.end = &end
+		return 1 + n
+	}
+	return 0
+}
+
+// matchCount reads "10 times"
+func (p *parser) matchCount(i int, _ string) int {
+	v, err := strconv.Atoi(p.word(i))
+	if err != nil || v <= 0 || p.word(i+1) != "times" {
+		return 0
+	}
+	if p.count != 0 {
+		p.repeated = true
+	}
+	p.count = v
+	return 2
+}
+
+func (p *parser) matchDate(i int, _ string) int {
+	d, kind, n := p.dateAt(i)
+	if n == 0 {
+		return 0
+	}
+	if p.date != nil {
+		p.repeated = true
+	}
+	p.date, p.kind = &d, kind
+	return n
+}
+
+// dateAt reads the date starting at token i, returning its midnight and
+// how many tokens it took
+func (p *parser) dateAt(i int) (time.Time, dateKind, int) {
+	loc := p.now.Location()
+	today := time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, loc)
+
+	w := p.word(i)
+	switch w {
+	case "today", "tonight":
+		return today, dateExplicit, 1
+	case "tomorrow", "tmrw", "tmr":
+		return today.AddDate(0, 0, 1), dateExplicit, 1
+	case "day":
+		if p.word(i+1) == "after" && p.word(i+2) == "tomorrow" {
+			return today.AddDate(0, 0, 2), dateExplicit, 3
+		}
+	case "next", "this":
+		if wd, plural, ok := weekdayOf(p.word(i + 1)); ok && !plural {
+			d := onOrAfter(today, wd)
+			if w == "this" {
+				return d, dateWeekday, 2
+			}
+			if d.Equal(today) {
+				d = d.AddDate(0, 0, 7)
+			}
+			return d, dateExplicit, 2
+		}
+	}
+
+	if wd, plural, ok := weekdayOf(w); ok && !plural {
+		return onOrAfter(today, wd), dateWeekday, 1
+	}
+	if isoDatePattern.MatchString(w) {
+		if d, err := time.ParseInLocation("2006-01-02", w, loc); err == nil {
+			return d, dateExplicit, 1
+		}
+	}
+
+	// "5 aug", "5th of august", "aug 5", each optionally followed by a year
+	if m := dayOfMonthPattern.FindStringSubmatch(w); m != nil {
+		k := i + 1
+		if p.word(k) == "of" {
+			k++
+		}
+		if month, ok := months[p.word(k)]; ok {
+			day, _ := strconv.Atoi(m[1])
+			if d, end, ok := p.calendarDate(today, day, month, k+1); ok {
+				return d, dateExplicit, end - i
+			}
+		}
+	}
+	if month, ok := months[w]; ok {
+		if m := dayOfMonthPattern.FindStringSubmatch(p.word(i + 1)); m != nil {
+			day, _ := strconv.Atoi(m[1])
+			if d, end, ok := p.calendarDate(today, day, month, i+2); ok {
+				return d, dateExplicit, end - i
+			}
+		}
+	}
+	return time.Time{}, dateNone, 0
+}
+
+// calendarDate builds a day of month, reading an optional year at token
+// next. Without a year the next such date on or after today is used. end
+// is the index after the last token read.
+func (p *parser) calendarDate(today time.Time, day int, month time.Month, next int) (time.Time, int, bool) {
+	year, end := today.Year(), next
+	explicitYear := false
+	if w := p.word(next); yearPattern.MatchString(w) {
+		year, _ = strconv.Atoi(w)
+		end, explicitYear = next+1, true
+	}
+
+	d := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+	if d.Day() != day {
+		return time.Time{}, 0, false
+	}
+	if !explicitYear && d.Before(today) {
+		d = time.Date(year+1, month, day, 0, 0, 0, 0, today.Location())
+	}
+	return d, end, true
+}
+
+// matchTime reads a start time or a time range. A bare hour like "9" is only
+// taken as a time after "at", "from" or "between", or as part of a range
+// whose other end is unambiguous.
+func (p *parser) matchTime(i int, prefix string) int {
+	bare := prefix == "at" || prefix == "@" || prefix == "from" || prefix == "between"
+
+	// "9-10am", "12:30-13:30"
+	if a, b, ok := strings.Cut(p.word(i), "-"); ok {
+		first, ok1 := readClock(a)
+		second, ok2 := readClock(b)
+		if !ok1 || !ok2 {
+			return 0
+		}
+		n := 1
+		if second.meridiem == "" {
+			if w := p.word(i + 1); w == "am" || w == "pm" {
+				second.meridiem, second.explicit = w, true
+				n = 2
+			}
+		}
+		if (first.explicit || second.explicit || bare) && p.setRange(first, second) {
+			return n
+		}
+		return 0
+	}
+
+	first, n := p.clockAt(i)
+	if n == 0 {
+		return 0
+	}
+
+	// "9 to 10:30", "3pm - 4pm", "between 9 and 11"
+	sep := p.word(i + n)
+	if sep == "-" || sep == "to" || sep == "until" || sep == "till" || (prefix == "between" && sep == "and") {
+		if second, m := p.clockAt(i + n + 1); m > 0 && (first.explicit || second.explicit || bare) {
+			if p.setRange(first, second) {
+				return n + 1 + m
+			}
+			return 0
+		}
+	}
+
+	if (!first.explicit && !bare) || prefix == "between" {
+		return 0
+	}
+	start, ok := first.resolve()
+	if !ok {
+		return 0
+	}
+	if p.start != nil {
+		p.repeated = true
+	}
+	p.start = &start
+	return n
+}
+
+// setRange sets the start and end of a time range. A start without am/pm
+// takes the end's, as in "9-10am", unless that would put it after the end.
+func (p *parser) setRange(first, second clockText) bool {
+	end, ok := second.resolve()
+	if !ok {
+		return false
+	}
+	if first.meridiem == "" && second.meridiem != "" {
+		candidate := first
+		candidate.meridiem = second.meridiem
+		if c, ok := candidate.resolve(); ok && !after(c, end) {
+			first = candidate
+		}
+	}
+	start, ok := first.resolve()
+	if !ok {
+		return false
+	}
+	if p.start != nil || p.end != nil {
+		p.repeated = true
+	}
+	p.start, p.end = &start, &end
+	return true
+}
+
+// clockAt reads a time of day at token i, with "am" or "pm" as the next
+// token when it is not attached
+func (p *parser) clockAt(i int) (clockText, int) {
+	c, ok := readClock(p.word(i))
+	if !ok {
+		return clockText{}, 0
+	}
+	if c.meridiem == "" && (c.hour >= 1 && c.hour <= 12) {
+		if w := p.word(i + 1); w == "am" || w == "pm" {
+			c.meridiem, c.explicit = w, true
+			return c, 2
+		}
+	}
+	return c, 1
+}
+
+func readClock(s string) (clockText, bool) {
+	switch s {
+	case "noon", "midday":
+		return clockText{hour: 12, explicit: true}, true
+	case "midnight":
+		return clockText{hour: 0, explicit: true}, true
+	}
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil {
+		return clockText{}, false
+	}
+	c := clockText{meridiem: m[3]}
+	c.hour, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		c.minute, _ = strconv.Atoi(m[2])
+	}
+	c.explicit = m[2] != "" || m[3] != ""
+	return c, true
+}
+
+// matchDuration reads "1h30m", "90 min", "1.5 hours", "an hour" or
+// "half an hour"
+func (p *parser) matchDuration(i int, _ string) int {
+	var d time.Duration
+	n := 0
+
+	switch w := p.word(i); {
+	case w == "an" || w == "a" || w == "one":
+		if hourUnits[p.word(i+1)] {
+			d, n = time.Hour, 2
+		}
+	case w == "half":
+		k := i + 1
+		if v := p.word(k); v == "an" || v == "a" {
+			k++
+		}
+		if hourUnits[p.word(k)] {
+			d, n = 30*time.Minute, k-i+1
+		}
+	default:
+		if m := durationPattern.FindStringSubmatch(w); m != nil && (m[1] != "" || m[2] != "") {
+			if m[1] != "" {
+				hours, _ := strconv.ParseFloat(m[1], 64)
+				d += time.Duration(hours * float64(time.Hour))
+			}
+			if m[2] != "" {
+				minutes, _ := strconv.Atoi(m[2])
+				d += time.Duration(minutes) * time.Minute
+			}
+			n = 1
+		} else if v, err := strconv.ParseFloat(w, 64); err == nil && v > 0 {
+			switch unit := p.word(i + 1); {
+			case hourUnits[unit]:
+				d, n = time.Duration(v*float64(time.Hour)), 2
+			case minuteUnits[unit]:
+				d, n = time.Duration(v*float64(time.Minute)), 2
+			}
+		}
+	}
+
+	if n == 0 || d <= 0 {
+		return 0
+	}
+	if p.duration != 0 {
+		p.repeated = true
+	}
+	p.duration = d.Round(time.Minute)
+	return n
+}
+
+func (p *parser) build() (*Item, error) {
+	if p.repeated {
+		return nil, fmt.Errorf("%w: a date, time or recurrence is given more than once", ErrUnrecognized)
+	}
+	if p.start == nil {
+		return nil, fmt.Errorf("%w: no start time", ErrUnrecognized)
+	}
+	title, err := p.title()
+	if err != nil {
+		return nil, err
+	}
+
+	loc := p.now.Location()
+	day := time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, loc)
+	if p.date != nil {
+		day = *p.date
+	}
+	start := at(day, *p.start)
+	if start.Before(p.now) {
+		switch p.kind {
+		case dateNone:
+			day = day.AddDate(0, 0, 1)
+		case dateWeekday:
+			day = day.AddDate(0, 0, 7)
+		}
+		start = at(day, *p.start)
+	}
+
+	var end time.Time
+	switch {
+	case p.end != nil:
+		end = at(day, *p.end)
+		if !end.After(start) {
+			end = at(day.AddDate(0, 0, 1), *p.end)
+		}
+	case p.duration > 0:
+		end = start.Add(p.duration)
+	default:
+		end = start.Add(DefaultDuration)
+	}
+
+	item := &Item{Title: title, Start: start, End: end}
+	if p.freq == "" {
+		if p.count > 0 || p.until != nil {
+			return nil, fmt.Errorf("%w: an end of recurrence without a recurrence", ErrUnrecognized)
+		}
+		return item, nil
+	}
+	if p.count > 0 && p.until != nil {
+		return nil, fmt.Errorf("%w: both a count and an end date", ErrUnrecognized)
+	}
+
+	parts := []string{"FREQ=" + string(p.freq)}
+	if p.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", p.interval))
+	}
+	if len(p.byDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(p.byDay, ","))
+	}
+	if p.count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", p.count))
+	}
+	if p.until != nil {
+		until := time.Date(p.until.Year(), p.until.Month(), p.until.Day(), 23, 59, 59, 0, loc)
+		parts = append(parts, "UNTIL="+until.UTC().Format("20060102T150405Z"))
+	}
+	rule, err := rrule.Parse(strings.Join(parts, ";"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnrecognized, err)
+	}
+
+	// The rule only yields the start when the start matches it, so begin at
+	// the first occurrence instead, e.g. the first Friday from tomorrow on
+	first := rule.After(start, start.Add(-time.Nanosecond))
+	if first == nil {
+		return nil, fmt.Errorf("%w: the recurrence ends before it starts", ErrUnrecognized)
+	}
+	length := end.Sub(start)
+	item.Start, item.End = *first, first.Add(length)
+	item.RRule = rule.String()
+	return item, nil
+}
+
+// title joins the words no detail was read from. Leftover numbers,
+// weekdays and the like mean part of the text was not understood.
+func (p *parser) title() (string, error) {
+	var words []token
+	for _, t := range p.tokens {
+		if !t.used {
+			words = append(words, t)
+		}
+	}
+	for len(words) > 0 && fillers[words[0].lower] {
+		words = words[1:]
+	}
+	for len(words) > 0 && fillers[words[len(words)-1].lower] {
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		return "", fmt.Errorf("%w: no title", ErrUnrecognized)
+	}
+
+	parts := make([]string, len(words))
+	for i, t := range words {
+		if strings.ContainsAny(t.lower, "0123456789") {
+			return "", fmt.Errorf("%w: %q", ErrUnrecognized, t.text)
+		}
+		if _, _, ok := weekdayOf(t.lower); ok {
+			return "", fmt.Errorf("%w: %q", ErrUnrecognized, t.text)
+		}
+		switch t.lower {
+		case "am", "pm", "every", "next":
+			return "", fmt.Errorf("%w: %q", ErrUnrecognized, t.text)
+		}
+		parts[i] = t.text
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// weekdayOf reads a weekday name, plural meaning "mondays"
+func weekdayOf(w string) (time.Weekday, bool, bool) {
+	if wd, ok := weekdays[w]; ok {
+		return wd, false, true
+	}
+	if strings.HasSuffix(w, "s") {
+		if wd, ok := weekdays[strings.TrimSuffix(w, "s")]; ok {
+			return wd, true, true
+		}
+	}
+	return 0, false, false
+}
+
+func onOrAfter(day time.Time, wd time.Weekday) time.Time {
+	return day.AddDate(0, 0, (int(wd)-int(day.Weekday())+7)%7)
+}
+
+func at(day time.Time, c clock) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), c.hour, c.minute, 0, 0, day.Location())
+}
+
+func after(a, b clock) bool {
+	return a.hour > b.hour || (a.hour == b.hour && a.minute > b.minute)
+}
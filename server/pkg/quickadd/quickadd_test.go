@@ -0,0 +1,98 @@
+package quickadd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"murim-helper/pkg/quickadd"
+)
+
+// now is Wednesday 2025-08-06 10:00 in a zone without DST
+var now = time.Date(2025, 8, 6, 10, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+const layout = "2006-01-02 15:04"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text       string
+		title      string
+		start, end string
+		rrule      string
+	}{
+		// The first Friday from tomorrow on
+		{"lunch with Budi tomorrow 12:30 for 1h every Friday", "lunch with Budi", "2025-08-08 12:30", "2025-08-08 13:30", "FREQ=WEEKLY;BYDAY=FR"},
+
+		// A time that already passed today moves to tomorrow
+		{"standup at 9", "standup", "2025-08-07 09:00", "2025-08-07 10:00", ""},
+		{"standup 9am daily", "standup", "2025-08-07 09:00", "2025-08-07 10:00", "FREQ=DAILY"},
+		{"review on 2025-08-20 at 9 for 90 min", "review", "2025-08-20 09:00", "2025-08-20 10:30", ""},
+		{"retro next wednesday 4pm for half an hour", "retro", "2025-08-13 16:00", "2025-08-13 16:30", ""},
+
+		// am/pm around 12
+		{"feed the cat 12am", "feed the cat", "2025-08-07 00:00", "2025-08-07 01:00", ""},
+		{"call mom tomorrow 12:30am", "call mom", "2025-08-07 00:30", "2025-08-07 01:30", ""},
+		{"lunch 12pm", "lunch", "2025-08-06 12:00", "2025-08-06 13:00", ""},
+		{"lunch at noon", "lunch", "2025-08-06 12:00", "2025-08-06 13:00", ""},
+		{"nap 12-1pm", "nap", "2025-08-06 12:00", "2025-08-06 13:00", ""},
+		{"meeting 11-12pm", "meeting", "2025-08-06 11:00", "2025-08-06 12:00", ""},
+		{"class 9-10am tomorrow", "class", "2025-08-07 09:00", "2025-08-07 10:00", ""},
+
+		// Ranges that cross midnight end the next day
+		{"night shift 11pm-7am", "night shift", "2025-08-06 23:00", "2025-08-07 07:00", ""},
+		{"sleep from 10pm to 6am", "sleep", "2025-08-06 22:00", "2025-08-07 06:00", ""},
+		{"party friday 9pm until 1am", "party", "2025-08-08 21:00", "2025-08-09 01:00", ""},
+
+		// Ends of recurrence
+		{"gym 7am every mon and wed until aug 31", "gym", "2025-08-11 07:00", "2025-08-11 08:00", "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20250831T165959Z"},
+		{"physio 3pm every 2 weeks 10 times", "physio", "2025-08-06 15:00", "2025-08-06 16:00", "FREQ=WEEKLY;INTERVAL=2;COUNT=10"},
+		{"rent monthly on aug 25 at 9am 12 times", "rent", "2025-08-25 09:00", "2025-08-25 10:00", "FREQ=MONTHLY;COUNT=12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			item, err := quickadd.Parse(tt.text, now)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if item.Title != tt.title {
+				t.Errorf("title = %q, want %q", item.Title, tt.title)
+			}
+			if got := item.Start.Format(layout); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := item.End.Format(layout); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+			if item.RRule != tt.rrule {
+				t.Errorf("rrule = %q, want %q", item.RRule, tt.rrule)
+			}
+		})
+	}
+}
+
+func TestParseUnrecognized(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"lunch with Budi",
+		"3pm tomorrow",
+		"lunch at 25:00",
+		"meeting 13pm",
+		"meeting 3pm 4pm",
+		"call Budi tomorrow at 3pm tuesday",
+		"standup 9am daily weekly",
+		"dentist 3pm 5 times",
+		"dentist 3pm until aug 31",
+		"gym 7am daily 3 times until aug 31",
+		"meeting at 9 next",
+		"lunch feb 30 at noon",
+		"gym 7am daily until 2025-08-01",
+	} {
+		t.Run(text, func(t *testing.T) {
+			item, err := quickadd.Parse(text, now)
+			if !errors.Is(err, quickadd.ErrUnrecognized) {
+				t.Errorf("got %+v, %v; want ErrUnrecognized", item, err)
+			}
+		})
+	}
+}
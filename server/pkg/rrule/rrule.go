@@ -0,0 +1,533 @@
+// Package rrule parses and expands the subset of RFC 5545 recurrence rules
+// used by schedules: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT,
+// UNTIL, BYDAY (optionally with an ordinal such as 2MO or -1FR), BYMONTHDAY
+// and BYMONTH.
+//
+// Occurrences are built from the wall-clock time of DTSTART in its own
+// location, so a 07:00 event stays at 07:00 across DST changes. A wall-clock
+// time skipped by a DST change is moved forward by the length of the gap
+// rather than dropped as RFC 5545 asks.
+//
+// Unlike RFC 5545, DTSTART is not always the first occurrence: it is only
+// returned, and only counted towards COUNT, when it matches the rule. A
+// weekly Monday rule starting on a Wednesday first occurs the next Monday.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds expansion so a rule that can never match (e.g. the 31st
+// of February) cannot loop forever.
+const maxPeriods = 100000
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month (or year);
+// 0 means every such weekday.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int        // 0 means unbounded
+	Until      *time.Time // inclusive
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+
+	// untilFloating is set when UNTIL had no "Z" suffix; it is then read
+	// in the location of DTSTART.
+	untilFloating bool
+	untilRaw      string
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=10".
+// A leading "RRULE:" is accepted.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "RRULE:"), "rrule:")
+	if value == "" {
+		return nil, errors.New("rrule is empty")
+	}
+
+	r := &Rule{Interval: 1}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("rrule: malformed part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if seen[key] {
+			return nil, fmt.Errorf("rrule: %s given more than once", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch f := Frequency(val); f {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = f
+			default:
+				err = fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+			if err == nil && r.Interval < 1 {
+				err = errors.New("INTERVAL must be at least 1")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+			if err == nil && r.Count < 1 {
+				err = errors.New("COUNT must be at least 1")
+			}
+		case "UNTIL":
+			err = r.parseUntil(val)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(val, -31, 31)
+		case "BYMONTH":
+			r.ByMonth, err = parseInts(val, 1, 12)
+		case "WKST":
+			if val != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rrule: %w", err)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("rrule: FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, errors.New("rrule: COUNT and UNTIL cannot both be set")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, errors.New("rrule: BYDAY ordinals are only allowed with FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return nil, errors.New("rrule: BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+	if r.Freq == Yearly && len(r.ByDay) > 0 && len(r.ByMonth) == 0 {
+		return nil, errors.New("rrule: FREQ=YEARLY with BYDAY requires BYMONTH")
+	}
+	return r, nil
+}
+
+func (r *Rule) parseUntil(val string) error {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		t, err := time.Parse(layout, val)
+		if err != nil {
+			continue
+		}
+		if layout == "20060102" {
+			// A date-only UNTIL includes the whole day.
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		r.Until = &t
+		r.untilFloating = !strings.HasSuffix(val, "Z")
+		r.untilRaw = val
+		return nil
+	}
+	return fmt.Errorf("invalid UNTIL %q", val)
+}
+
+func parseByDay(val string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		wd, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid BYDAY ordinal %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{Weekday: wd, N: n})
+	}
+	return days, nil
+}
+
+func parseInts(val string, min, max int) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("invalid value %q (allowed %d..%d)", item, min, max)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// String returns the canonical RRULE form of r.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayNames[d.Weekday]
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		until := r.untilRaw
+		if until == "" {
+			until = r.Until.UTC().Format("20060102T150405Z")
+		}
+		parts = append(parts, "UNTIL="+until)
+	}
+	return strings.Join(parts, ";")
+}
+
+// SetUntil bounds the rule at t (inclusive), replacing any COUNT. A nil t
+// removes the bound.
+func (r *Rule) SetUntil(t *time.Time) {
+	r.untilFloating = false
+	r.untilRaw = ""
+	if t == nil {
+		r.Until = nil
+		return
+	}
+	u := t.UTC()
+	r.Until = &u
+	r.Count = 0
+}
+
+// UntilIn returns UNTIL resolved in loc, which matters for floating values.
+func (r *Rule) UntilIn(loc *time.Location) *time.Time {
+	if r.Until == nil {
+		return nil
+	}
+	if !r.untilFloating {
+		return r.Until
+	}
+	u := r.Until
+	t := time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), 0, loc)
+	return &t
+}
+
+// Between returns the occurrences of the rule anchored at dtstart whose
+// start falls in [from, to). dtstart is only an occurrence when it matches
+// the rule; see the package comment.
+func (r *Rule) Between(dtstart, from, to time.Time) []time.Time {
+	var out []time.Time
+	r.iterate(dtstart, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			out = append(out, t)
+		}
+		return true
+	})
+	return out
+}
+
+// After returns the first occurrence strictly after t, or nil when the rule
+// has ended.
+func (r *Rule) After(dtstart, t time.Time) *time.Time {
+	var next *time.Time
+	r.iterate(dtstart, func(occ time.Time) bool {
+		if occ.After(t) {
+			next = &occ
+			return false
+		}
+		return true
+	})
+	return next
+}
+
+// Last returns the final occurrence of a bounded rule, or nil when the rule
+// repeats forever.
+func (r *Rule) Last(dtstart time.Time) *time.Time {
+	if r.Count == 0 && r.Until == nil {
+		return nil
+	}
+	var last *time.Time
+	r.iterate(dtstart, func(occ time.Time) bool {
+		o := occ
+		last = &o
+		return true
+	})
+	return last
+}
+
+// iterate calls yield for each occurrence in order until it returns false
+// or the rule ends.
+func (r *Rule) iterate(dtstart time.Time, yield func(time.Time) bool) {
+	loc := dtstart.Location()
+	until := r.UntilIn(loc)
+	emitted := 0
+	misses := 0
+
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.periodCandidates(dtstart, period)
+		if len(candidates) == 0 {
+			misses++
+			if misses > maxPeriods/10 {
+				return
+			}
+			continue
+		}
+		misses = 0
+
+		for _, c := range candidates {
+			if c.Before(dtstart) {
+				continue
+			}
+			if until != nil && c.After(*until) {
+				return
+			}
+			if !yield(c) {
+				return
+			}
+			emitted++
+			if r.Count > 0 && emitted >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// periodCandidates returns the sorted occurrences that fall in the n-th
+// period (day, week, month or year) counted from dtstart.
+func (r *Rule) periodCandidates(dtstart time.Time, n int) []time.Time {
+	loc := dtstart.Location()
+	h, m, s := dtstart.Clock()
+	at := func(y int, mo time.Month, d int) time.Time {
+		return wallClock(y, mo, d, h, m, s, loc)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		d := at(dtstart.Year(), dtstart.Month(), dtstart.Day()+n*r.Interval)
+		if r.matchesMonth(d) && r.matchesMonthDay(d) && r.matchesWeekday(d) {
+			days = append(days, d)
+		}
+
+	case Weekly:
+		// Weeks start on Monday (WKST=MO).
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := at(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+n*7*r.Interval)
+		for i := 0; i < 7; i++ {
+			d := at(monday.Year(), monday.Month(), monday.Day()+i)
+			if len(r.ByDay) == 0 {
+				if d.Weekday() != dtstart.Weekday() {
+					continue
+				}
+			} else if !r.matchesWeekday(d) {
+				continue
+			}
+			if r.matchesMonth(d) {
+				days = append(days, d)
+			}
+		}
+
+	case Monthly:
+		first := at(dtstart.Year(), dtstart.Month()+time.Month(n*r.Interval), 1)
+		if !r.matchesMonth(first) {
+			return nil
+		}
+		days = r.daysInMonth(first, dtstart)
+
+	case Yearly:
+		year := dtstart.Year() + n*r.Interval
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []int{int(dtstart.Month())}
+		}
+		for _, mo := range months {
+			days = append(days, r.daysInMonth(at(year, time.Month(mo), 1), dtstart)...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return dedupe(days)
+}
+
+// daysInMonth expands BYMONTHDAY / BYDAY within the month starting at first.
+// Without either, the day of month of dtstart is used and months that are too
+// short are skipped, as RFC 5545 requires.
+func (r *Rule) daysInMonth(first, dtstart time.Time) []time.Time {
+	y, mo := first.Year(), first.Month()
+	h, m, s := dtstart.Clock()
+	loc := dtstart.Location()
+	last := time.Date(y, mo+1, 0, 0, 0, 0, 0, loc).Day()
+	at := func(d int) time.Time { return wallClock(y, mo, d, h, m, s, loc) }
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			d := md
+			if md < 0 {
+				d = last + md + 1
+			}
+			if d < 1 || d > last {
+				continue
+			}
+			t := at(d)
+			if len(r.ByDay) == 0 || r.matchesWeekday(t) {
+				days = append(days, t)
+			}
+		}
+
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []int
+			for d := 1; d <= last; d++ {
+				if at(d).Weekday() == wd.Weekday {
+					matches = append(matches, d)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				for _, d := range matches {
+					days = append(days, at(d))
+				}
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, at(matches[wd.N-1]))
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, at(matches[len(matches)+wd.N]))
+			}
+		}
+
+	default:
+		if dtstart.Day() <= last {
+			days = append(days, at(dtstart.Day()))
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, d := range r.ByDay {
+		if d.Weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// wallClock is time.Date for an occurrence. time.Date may resolve a time
+// skipped by a DST change to before the gap; it is moved past it instead.
+func wallClock(y int, mo time.Month, d, h, m, s int, loc *time.Location) time.Time {
+	t := time.Date(y, mo, d, h, m, s, 0, loc)
+	want := time.Date(y, mo, d, h, m, s, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if got.Before(want) {
+		t = t.Add(want.Sub(got))
+	}
+	return t
+}
+
+func (r *Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, md := range r.ByMonthDay {
+		if md == t.Day() || (md < 0 && last+md+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonth(t time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if time.Month(m) == t.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(days []time.Time) []time.Time {
+	if len(days) < 2 {
+		return days
+	}
+	out := days[:1]
+	for _, d := range days[1:] {
+		if !d.Equal(out[len(out)-1]) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,280 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func format(times []time.Time) []string {
+	out := make([]string, len(times))
+	for i, t := range times {
+		out[i] = t.Format("2006-01-02 15:04 MST")
+	}
+	return out
+}
+
+func TestExpand(t *testing.T) {
+	utc := time.UTC
+	ny := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []string
+	}{
+		{
+			name:    "daily with interval",
+			rule:    "FREQ=DAILY;INTERVAL=2;COUNT=4",
+			dtstart: time.Date(2025, 1, 1, 9, 0, 0, 0, utc),
+			want:    []string{"2025-01-01 09:00 UTC", "2025-01-03 09:00 UTC", "2025-01-05 09:00 UTC", "2025-01-07 09:00 UTC"},
+		},
+		{
+			name:    "weekly on weekdays",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5",
+			dtstart: time.Date(2025, 8, 4, 9, 0, 0, 0, utc), // Monday
+			want:    []string{"2025-08-04 09:00 UTC", "2025-08-06 09:00 UTC", "2025-08-08 09:00 UTC", "2025-08-11 09:00 UTC", "2025-08-13 09:00 UTC"},
+		},
+		{
+			name:    "every other week",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;COUNT=4",
+			dtstart: time.Date(2025, 8, 4, 9, 0, 0, 0, utc),
+			want:    []string{"2025-08-05 09:00 UTC", "2025-08-07 09:00 UTC", "2025-08-19 09:00 UTC", "2025-08-21 09:00 UTC"},
+		},
+		{
+			name:    "weekly defaults to the weekday of dtstart",
+			rule:    "FREQ=WEEKLY;COUNT=3",
+			dtstart: time.Date(2025, 8, 6, 9, 0, 0, 0, utc),
+			want:    []string{"2025-08-06 09:00 UTC", "2025-08-13 09:00 UTC", "2025-08-20 09:00 UTC"},
+		},
+		{
+			name:    "second Monday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=2MO;COUNT=3",
+			dtstart: time.Date(2025, 1, 13, 18, 30, 0, 0, utc),
+			want:    []string{"2025-01-13 18:30 UTC", "2025-02-10 18:30 UTC", "2025-03-10 18:30 UTC"},
+		},
+		{
+			name:    "last Friday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: time.Date(2025, 1, 31, 17, 0, 0, 0, utc),
+			want:    []string{"2025-01-31 17:00 UTC", "2025-02-28 17:00 UTC", "2025-03-28 17:00 UTC"},
+		},
+		{
+			name:    "BYMONTHDAY=31 skips short months",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=4",
+			dtstart: time.Date(2025, 1, 31, 9, 0, 0, 0, utc),
+			want:    []string{"2025-01-31 09:00 UTC", "2025-03-31 09:00 UTC", "2025-05-31 09:00 UTC", "2025-07-31 09:00 UTC"},
+		},
+		{
+			name:    "monthly on the 31st of dtstart skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: time.Date(2025, 1, 31, 9, 0, 0, 0, utc),
+			want:    []string{"2025-01-31 09:00 UTC", "2025-03-31 09:00 UTC", "2025-05-31 09:00 UTC"},
+		},
+		{
+			name:    "last day of the month",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			dtstart: time.Date(2025, 1, 31, 9, 0, 0, 0, utc),
+			want:    []string{"2025-01-31 09:00 UTC", "2025-02-28 09:00 UTC", "2025-03-31 09:00 UTC"},
+		},
+		{
+			name:    "Friday the 13th",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=FR;COUNT=2",
+			dtstart: time.Date(2025, 1, 1, 9, 0, 0, 0, utc),
+			want:    []string{"2025-06-13 09:00 UTC", "2026-02-13 09:00 UTC"},
+		},
+		{
+			name:    "UNTIL is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20250103T090000Z",
+			dtstart: time.Date(2025, 1, 1, 9, 0, 0, 0, utc),
+			want:    []string{"2025-01-01 09:00 UTC", "2025-01-02 09:00 UTC", "2025-01-03 09:00 UTC"},
+		},
+		{
+			name:    "date-only UNTIL includes the whole day",
+			rule:    "FREQ=DAILY;UNTIL=20250103",
+			dtstart: time.Date(2025, 1, 1, 21, 0, 0, 0, utc),
+			want:    []string{"2025-01-01 21:00 UTC", "2025-01-02 21:00 UTC", "2025-01-03 21:00 UTC"},
+		},
+		{
+			name:    "floating UNTIL is read in the zone of dtstart",
+			rule:    "FREQ=DAILY;UNTIL=20250103T070000",
+			dtstart: time.Date(2025, 1, 1, 7, 0, 0, 0, ny),
+			want:    []string{"2025-01-01 07:00 EST", "2025-01-02 07:00 EST", "2025-01-03 07:00 EST"},
+		},
+		{
+			name:    "COUNT counts occurrences, not periods",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=30;COUNT=2",
+			dtstart: time.Date(2025, 1, 30, 9, 0, 0, 0, utc),
+			want:    []string{"2025-01-30 09:00 UTC", "2025-03-30 09:00 UTC"},
+		},
+		{
+			name:    "yearly on Feb 29 only in leap years",
+			rule:    "FREQ=YEARLY;COUNT=3",
+			dtstart: time.Date(2024, 2, 29, 8, 0, 0, 0, utc),
+			want:    []string{"2024-02-29 08:00 UTC", "2028-02-29 08:00 UTC", "2032-02-29 08:00 UTC"},
+		},
+		{
+			name:    "yearly by month and weekday",
+			rule:    "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH;COUNT=2",
+			dtstart: time.Date(2025, 11, 27, 12, 0, 0, 0, utc),
+			want:    []string{"2025-11-27 12:00 UTC", "2026-11-26 12:00 UTC"},
+		},
+		{
+			name:    "wall clock kept when DST starts",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: time.Date(2025, 3, 8, 7, 0, 0, 0, ny),
+			want:    []string{"2025-03-08 07:00 EST", "2025-03-09 07:00 EDT", "2025-03-10 07:00 EDT"},
+		},
+		{
+			name:    "wall clock kept when DST ends",
+			rule:    "FREQ=WEEKLY;COUNT=2",
+			dtstart: time.Date(2025, 10, 28, 7, 0, 0, 0, ny),
+			want:    []string{"2025-10-28 07:00 EDT", "2025-11-04 07:00 EST"},
+		},
+		{
+			// RFC 5545 always counts DTSTART as the first occurrence; see the
+			// package comment
+			name:    "dtstart that does not match the rule is skipped",
+			rule:    "FREQ=WEEKLY;BYDAY=MO;COUNT=2",
+			dtstart: time.Date(2025, 8, 6, 9, 0, 0, 0, utc), // Wednesday
+			want:    []string{"2025-08-11 09:00 UTC", "2025-08-18 09:00 UTC"},
+		},
+		{
+			name:    "rule that can never match ends",
+			rule:    "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+			dtstart: time.Date(2025, 1, 1, 9, 0, 0, 0, utc),
+			want:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got := format(r.Between(tt.dtstart, tt.dtstart, tt.dtstart.AddDate(10, 0, 0)))
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetweenAfterLast(t *testing.T) {
+	dtstart := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	r, err := Parse("FREQ=DAILY;COUNT=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The window is half-open and COUNT still counts from dtstart
+	got := format(r.Between(dtstart, dtstart.AddDate(0, 0, 3), dtstart.AddDate(0, 0, 10)))
+	want := []string{"2025-01-04 09:00 UTC", "2025-01-05 09:00 UTC"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Between: got %q, want %q", got, want)
+	}
+	if got := r.Between(dtstart, dtstart, dtstart); len(got) != 0 {
+		t.Errorf("Between an empty window: got %q", format(got))
+	}
+
+	if next := r.After(dtstart, dtstart); next == nil || !next.Equal(dtstart.AddDate(0, 0, 1)) {
+		t.Errorf("After dtstart: got %v", next)
+	}
+	if next := r.After(dtstart, dtstart.AddDate(0, 0, 4)); next != nil {
+		t.Errorf("After the last occurrence: got %v, want nil", next)
+	}
+	if last := r.Last(dtstart); last == nil || !last.Equal(dtstart.AddDate(0, 0, 4)) {
+		t.Errorf("Last: got %v", last)
+	}
+
+	forever, _ := Parse("FREQ=WEEKLY")
+	if last := forever.Last(dtstart); last != nil {
+		t.Errorf("Last of an unbounded rule: got %v, want nil", last)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;byday=mo,we;interval=1", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"FREQ=MONTHLY;COUNT=3;BYDAY=-1FR;INTERVAL=2", "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR;COUNT=3"},
+		{"FREQ=YEARLY;BYMONTHDAY=29;BYMONTH=2", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29"},
+		{"FREQ=DAILY;UNTIL=20250103", "FREQ=DAILY;UNTIL=20250103"},
+		{"FREQ=DAILY;WKST=MO", "FREQ=DAILY"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=DAILY;WKST=SU",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ",
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestSetUntil(t *testing.T) {
+	r, _ := Parse("FREQ=DAILY;COUNT=10")
+	until := time.Date(2025, 1, 3, 9, 0, 0, 0, time.UTC)
+	r.SetUntil(&until)
+	if got := r.String(); got != "FREQ=DAILY;UNTIL=20250103T090000Z" {
+		t.Errorf("after SetUntil: %q", got)
+	}
+	r.SetUntil(nil)
+	if got := r.String(); got != "FREQ=DAILY" {
+		t.Errorf("after SetUntil(nil): %q", got)
+	}
+}
+
+func TestSkippedWallClock(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	r, _ := Parse("FREQ=DAILY;COUNT=3")
+	dtstart := time.Date(2025, 3, 8, 2, 30, 0, 0, ny)
+
+	// 02:30 does not exist on 2025-03-09 and is moved to 03:30
+	got := format(r.Between(dtstart, dtstart, dtstart.AddDate(0, 0, 5)))
+	want := []string{"2025-03-08 02:30 EST", "2025-03-09 03:30 EDT", "2025-03-10 02:30 EDT"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}